@@ -17,7 +17,7 @@ func main() {
 	appParam, err := parser.InitAppMode(os.Args[1:])
 	if err != nil {
 		log.Printf("Failed to launch mygrep: %q", err.Error())
-		return
+		os.Exit(appmode.ExitTrouble)
 	}
 
 	// готовим слушатель прерываний - контекст для всего приложения
@@ -27,11 +27,16 @@ func main() {
 	// запуск приложения в указанном режиме
 	switch appParam.Mode {
 	case model.ModeMaster:
-		appmode.RunMaster(ctx, stop, appParam)
+		// код выхода как у GNU grep: 0 - есть выбранные строки, 1 - нет, 2 - была ошибка
+		code := appmode.RunMaster(ctx, stop, appParam)
+		stop()
+		os.Exit(code)
 	case model.ModeSlave:
-		appmode.RunSlave(ctx, stop, appParam)
+		code := appmode.RunSlave(ctx, stop, appParam)
+		stop()
+		os.Exit(code)
 	default:
 		log.Printf("Failed to launch mygrep: unknown mode %q specified.\nExiting the app...", appParam.Mode)
-		return
+		os.Exit(appmode.ExitTrouble)
 	}
 }
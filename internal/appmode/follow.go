@@ -0,0 +1,211 @@
+package appmode
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/printer"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/qaggr"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/docker/distribution/uuid"
+)
+
+// followFiles - режим --follow: файлы опрашиваются раз в ai.PollEvery, дописанные строки каждого файла
+// уходят slave-нодам отдельным заданием, а подтвержденные кворумом результаты печатаются сразу. С -A/-B
+// задание продолжает вывод контекста предыдущего задания того же файла(см. followState).
+// Работает до отмены ctx(SIGINT/SIGTERM) или до исчерпания лимита -m; возвращает код выхода
+func followFiles(ctx context.Context, ai *model.AppInit, client transport.Client, src []string, fileErrs *fileErrors) int {
+	gp := ai.SearchParam
+	opts := reader.Options{
+		NullData:          gp.NullData,
+		Offsets:           gp.ByteOffset,
+		MaxLineSize:       gp.MaxLineSize,
+		TruncateLongLines: gp.LongLines == model.LongLinesTruncate,
+	}
+
+	followers := make([]*reader.Follower, 0, len(src))
+	states := make([]*followState, 0, len(src))
+	for _, fname := range src {
+		followers = append(followers, reader.NewFollower(fname, opts))
+		states = append(states, &followState{})
+	}
+	defer func() {
+		for _, f := range followers {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+
+	out := printer.New(os.Stdout, gp.Color)
+	ticker := time.NewTicker(ai.PollEvery)
+	defer ticker.Stop()
+
+	selected := false
+	for {
+		tasks, owners := pollFollowers(ctx, followers, states, gp, fileErrs)
+		if len(tasks) != 0 {
+			result, err := processTasks(ctx, client, ai.Slaves, tasks, ai.Quorum, false, fileErrs)
+			for _, t := range tasks {
+				t.CancelCTX()
+			}
+			if ctx.Err() != nil { // Ctrl+C во время сбора результатов - обычное завершение --follow
+				return fileErrs.exitCode(selected)
+			}
+			if err != nil {
+				log.Printf("Failed to grep: %v", err)
+				return ExitTrouble
+			}
+			updateStates(tasks, owners, result, states)
+
+			if err := printResults(out, result); err != nil {
+				log.Printf("Failed to print result: %v", err)
+				return ExitTrouble
+			}
+			selected = selected || anySelected(&gp, result)
+
+			// -m: лимит общий на все время работы - следующие задания получают остаток
+			if gp.MaxCount != nil {
+				left := *gp.MaxCount - countSelected(result)
+				if left <= 0 {
+					return fileErrs.exitCode(selected)
+				}
+				gp.MaxCount = &left
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fileErrs.exitCode(selected)
+		case <-ticker.C:
+		}
+	}
+}
+
+// followState - вывод контекста -A/-B файла на конец его последнего задания: следующее задание начинается
+// с последних -B строк файла и продолжает вывод с того же места, как если бы файл искался целиком
+type followState struct {
+	lines   []string // последние строки файла(не больше -B), уже отправленные slave-нодам
+	offsets []int64  // их смещения от начала файла - только при -b
+	carry   model.ContextCarry
+}
+
+// pollFollowers опрашивает файлы и превращает новые строки в задания; owners[i] - номер файла задания tasks[i].
+// Файл, который не удалось прочитать, исключается из наблюдения
+func pollFollowers(ctx context.Context, followers []*reader.Follower, states []*followState, gp model.GrepParam,
+	fileErrs *fileErrors) (tasks []*model.MasterTask, owners []int) {
+	for i, f := range followers {
+		if f == nil {
+			continue
+		}
+		chunk, err := f.Poll()
+		if err != nil {
+			fileErrs.report((&reader.FileError{Path: f.Name(), Err: err}).Error())
+			f.Close()
+			followers[i] = nil
+			continue
+		}
+		if len(chunk.Lines) == 0 {
+			continue
+		}
+
+		tCTX, cancel := context.WithTimeout(ctx, 1*time.Minute)
+		task := model.TaskDTO{
+			TaskID:     uuid.Generate().String(),
+			GP:         gp,
+			Input:      chunk.Lines,
+			FileName:   f.Name(),
+			Binary:     reader.IsBinary(chunk.Lines),
+			Offsets:    chunk.Offsets,
+			BaseOffset: chunk.BaseOffset,
+			BaseLine:   chunk.BaseLine,
+		}
+		if gp.CarriesContext() {
+			states[i].prepend(&task, gp.CtxBefore)
+		}
+		tasks = append(tasks, &model.MasterTask{Task: task, CTX: tCTX, CancelCTX: cancel})
+		owners = append(owners, i)
+	}
+	return tasks, owners
+}
+
+// prepend добавляет в начало задания конец предыдущего задания файла и состояние вывода контекста на его конец,
+// а в state запоминает последние before строк файла для следующего задания
+func (fs *followState) prepend(task *model.TaskDTO, before int) {
+	lead := len(fs.lines)
+	carry := fs.carry
+	carry.Before = lead
+	task.Carry = &carry
+
+	var offsets []int64
+	if task.Offsets != nil {
+		offsets = append(make([]int64, 0, lead+len(task.Offsets)), fs.offsets...)
+		for _, off := range task.Offsets {
+			offsets = append(offsets, task.BaseOffset+off)
+		}
+	}
+	lines := append(append(make([]string, 0, lead+len(task.Input)), fs.lines...), task.Input...)
+
+	keep := max(len(lines)-before, 0)
+	fs.lines = slices.Clone(lines[keep:])
+	if offsets != nil {
+		fs.offsets = slices.Clone(offsets[keep:])
+	}
+
+	task.Input = lines
+	task.BaseLine -= lead
+	if offsets != nil {
+		task.BaseOffset = offsets[0]
+		for j := range offsets {
+			offsets[j] -= task.BaseOffset
+		}
+		task.Offsets = offsets
+	}
+}
+
+// updateStates запоминает состояние вывода контекста на конец заданий. Задания без результата в нем нет,
+// только если вывод пуст - тогда на его конец контекст после совпадения уже выведен
+func updateStates(tasks []*model.MasterTask, owners []int, result []qaggr.TaskResult, states []*followState) {
+	carries := make(map[string]*model.ContextCarry, len(result))
+	for _, v := range result {
+		carries[v.Task.Task.TaskID] = v.Carry
+	}
+	for i, t := range tasks {
+		if t.Task.Carry == nil {
+			continue
+		}
+		st := states[owners[i]]
+		if c := carries[t.Task.TaskID]; c != nil {
+			st.carry = model.ContextCarry{After: c.After, LastPrinted: c.LastPrinted}
+			continue
+		}
+		st.carry.After = 0
+	}
+}
+
+func printResults(out *printer.Printer, result []qaggr.TaskResult) error {
+	for _, v := range result {
+		if err := out.PrintNode(v.Node, &v.Task.Task, v.Output, v.Meta); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// countSelected считает выбранные строки в напечатанных результатах по их разметке
+func countSelected(result []qaggr.TaskResult) int {
+	n := 0
+	for _, v := range result {
+		for _, m := range v.Meta {
+			if m.Kind == model.LineSelected {
+				n++
+			}
+		}
+	}
+	return n
+}
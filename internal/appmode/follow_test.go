@@ -0,0 +1,46 @@
+package appmode
+
+import (
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/qaggr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowStatePrepend(t *testing.T) {
+	// файл "a\nbb\nccc\n" дописан тремя опросами: строки, их смещения от начала опроса и смещение опроса в файле
+	chunks := []model.TaskDTO{
+		{TaskID: "t1", Input: []string{"a"}, Offsets: []int64{0}, BaseOffset: 0, BaseLine: 0},
+		{TaskID: "t2", Input: []string{"bb"}, Offsets: []int64{0}, BaseOffset: 2, BaseLine: 1},
+		{TaskID: "t3", Input: []string{"ccc", "d"}, Offsets: []int64{0, 4}, BaseOffset: 5, BaseLine: 2},
+	}
+	wantTasks := []model.TaskDTO{
+		{TaskID: "t1", Input: []string{"a"}, Offsets: []int64{0}, BaseOffset: 0, BaseLine: 0, Carry: &model.ContextCarry{}},
+		{TaskID: "t2", Input: []string{"a", "bb"}, Offsets: []int64{0, 2}, BaseOffset: 0, BaseLine: 0, Carry: &model.ContextCarry{Before: 1, After: 1, LastPrinted: 1}},
+		{TaskID: "t3", Input: []string{"a", "bb", "ccc", "d"}, Offsets: []int64{0, 2, 5, 9}, BaseOffset: 0, BaseLine: 0, Carry: &model.ContextCarry{Before: 2, LastPrinted: 2}},
+	}
+	// результаты: t1 выбрал строку 1 и ждет строку контекста, t2 вывел ее, у t3 результата нет
+	results := map[string]*model.ContextCarry{
+		"t1": {After: 1, LastPrinted: 1},
+		"t2": {LastPrinted: 2},
+	}
+
+	st := &followState{}
+	for i, chunk := range chunks {
+		task := chunk
+		st.prepend(&task, 2)
+		require.Equal(t, wantTasks[i], task)
+
+		tasks := []*model.MasterTask{{Task: task}}
+		var result []qaggr.TaskResult
+		if c, ok := results[task.TaskID]; ok {
+			result = append(result, qaggr.TaskResult{Task: tasks[0], Output: []string{}, Carry: c})
+		}
+		updateStates(tasks, []int{0}, result, []*followState{st})
+	}
+	// в состоянии - только последние -B строк файла
+	require.Equal(t, []string{"ccc", "d"}, st.lines)
+	require.Equal(t, []int64{5, 9}, st.offsets)
+	require.Equal(t, model.ContextCarry{LastPrinted: 2}, st.carry)
+}
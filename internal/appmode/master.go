@@ -2,155 +2,275 @@
 package appmode
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/printer"
 	"github.com/UnendingLoop/DistributedGrepClone/internal/qaggr"
 	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
 	"github.com/docker/distribution/uuid"
 )
 
-func RunMaster(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) {
+// RunMaster выполняет поиск и возвращает код выхода как у GNU grep: ExitSelected, ExitNoSelected или ExitTrouble
+func RunMaster(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) int {
 	defer stop()
-	// прочитать все инпут-строки и преобразовать в задания
-	tasks, err := readInputConvertToTasks(ctx, ai.SearchParam.Source, ai.SearchParam)
+	fileErrs := newFileErrors(ai.SearchParam.NoMessages)
+
+	// клиент выбранного транспорта(--transport) для связи со slave-нодами; к нодам https:// - по TLS
+	tlsConf, err := transport.ClientTLSConfig(ai.TLS)
+	if err != nil {
+		log.Printf("Failed to start grepping: %v", err)
+		return ExitTrouble
+	}
+	metrics := &transport.Metrics{}
+	client, err := transport.NewClient(ai.Transport, transport.ClientOptions{
+		TLS:                 tlsConf,
+		Token:               ai.AuthToken,
+		Compress:            ai.Compress == model.CompressGzip,
+		MaxDecompressedSize: ai.MaxUnzipped,
+		Metrics:             metrics,
+	})
+	if err != nil {
+		log.Printf("Failed to start grepping: %v", err)
+		return ExitTrouble
+	}
+	defer client.Close()
+	if ai.Stats {
+		defer func() { fmt.Fprintf(os.Stderr, "mygrep: transfer: %s\n", metrics) }()
+	}
+
+	// --remote: файлы лежат на дисках slave-нод, мастер их не читает
+	if ai.SearchParam.Remote {
+		return runRemote(ctx, ai, client, fileErrs)
+	}
+
+	// --files-from/--files0-from с пустым списком: искать негде, а stdin читать нельзя
+	if ai.FilesFrom != "" && len(ai.SearchParam.Source) == 0 {
+		return ExitNoSelected
+	}
+
+	// раскрыть glob-шаблоны и каталоги(-r/-R) и отфильтровать файлы по --include/--exclude;
+	// отсутствующие файлы не мешают искать в остальных
+	src, problems := reader.ExpandSources(ai.SearchParam.Source, ai.SearchParam.Walk)
+	fileErrs.reportAll(problems)
+	if len(src) == 0 && (len(ai.SearchParam.Source) != 0 || ai.SearchParam.Walk.Recursive) {
+		return fileErrs.exitCode(false)
+	}
+	switch {
+	case ai.SearchParam.Walk.Recursive:
+		// как и GNU grep, имена файлов не печатаем только при поиске в единственном указанном файле
+		single := len(ai.SearchParam.Source) == 1 && len(src) == 1 && src[0] == ai.SearchParam.Source[0]
+		ai.SearchParam.PrintFileName = ai.SearchParam.PrintFileName || (!single && !ai.SearchParam.NoFileName)
+	case len(src) > 1: // glob-шаблон раскрылся в несколько файлов
+		ai.SearchParam.PrintFileName = ai.SearchParam.PrintFileName || !ai.SearchParam.NoFileName
+	}
+
+	// --follow: сначала проверяем slave-ноды, а затем читаем файлы по мере их роста
+	if ai.SearchParam.Follow {
+		if ai.Slaves, err = checkSlavesHealth(ctx, client, ai, ai.Quorum); err != nil {
+			log.Printf("Failed to start grepping: %v", err)
+			return ExitTrouble
+		}
+		return followFiles(ctx, ai, client, src, fileErrs)
+	}
+
+	// прочитать все инпут-строки и преобразовать в задания; файл, который не удалось прочитать, пропускается
+	tasks, err := readInputConvertToTasks(ctx, src, ai.SearchParam, ai.MaxOpen, fileErrs)
 	if err != nil {
 		log.Printf("Failed to read input: %v", err)
-		return
+		return ExitTrouble
+	}
+	if len(tasks) == 0 {
+		return fileErrs.exitCode(false)
 	}
 
 	// проверить пингом, что хотя бы минимальное кол-во slave-nodes доступны
-	if err := checkSlavesHealth(ctx, ai.Slaves, ai.Quorum); err != nil {
+	if ai.Slaves, err = checkSlavesHealth(ctx, client, ai, ai.Quorum); err != nil {
 		log.Printf("Failed to start grepping: %v", err)
-		return
+		return ExitTrouble
 	}
 
 	// асинхронно:
 	// - отправить всем зарегистрированным слейвам задания
 	// - получить результаты
-	result, err := processTasks(ctx, ai.Slaves, tasks, ai.Quorum)
+	result, err := processTasks(ctx, client, ai.Slaves, tasks, ai.Quorum, false, fileErrs)
 	if err != nil {
 		log.Printf("Failed to grep: %v", err)
-		return
+		return ExitTrouble
 	}
 
 	// печатаем результат
-	for _, v := range result {
-		for _, line := range v {
-			fmt.Println(line)
-		}
+	if err := printResults(printer.New(os.Stdout, ai.SearchParam.Color), result); err != nil {
+		log.Printf("Failed to print result: %v", err)
+		return ExitTrouble
 	}
+	return fileErrs.exitCode(anySelected(&ai.SearchParam, result))
 }
 
-func checkSlavesHealth(ctx context.Context, slavesAddr []string, quorumN int) error {
+// checkSlavesHealth пингует slave-ноды и возвращает те, которым можно отправлять задания. Недоступные ноды
+// остаются в списке, как и раньше: их задания просто не дойдут. Ноды с другой версией протокола или без
+// возможностей, нужных запросу, исключаются(--incompatible-nodes=skip) или останавливают поиск(=fail):
+// старая нода молча проигнорировала бы незнакомые параметры, и ее неверный результат мог бы выиграть кворум
+func checkSlavesHealth(ctx context.Context, client transport.Client, ai *model.AppInit, quorumN int) ([]string, error) {
 	wg := sync.WaitGroup{}
-	var goodSlaves atomic.Int64
 	rCtx, cancel := context.WithTimeout(ctx, 5*time.Second) // 5 секунд на обнаружение всех slave-nodes
 	defer cancel()
 
-	client := &http.Client{}
-
-	for _, v := range slavesAddr {
+	need := ai.SearchParam.RequiredFeatures()
+	infos := make([]*model.NodeInfo, len(ai.Slaves)) // nil - нода недоступна
+	for i, v := range ai.Slaves {
 		select {
 		case <-ctx.Done():
-			return nil
+			return ai.Slaves, nil
 		default:
-			if !strings.Contains(v, "http://") {
-				v = "http://" + v
-			}
 			wg.Add(1)
 			go func(addr string) {
 				defer wg.Done()
-				req, err := http.NewRequestWithContext(rCtx, "GET", addr+"/ping", nil)
-				if err != nil {
-					return
-				}
-
-				resp, err := client.Do(req)
+				info, err := client.Ping(rCtx, addr)
 				if err != nil {
+					log.Printf("slave-node %q is not available: %v", addr, err)
 					return
 				}
-				defer resp.Body.Close()
-
-				if resp.StatusCode == http.StatusOK {
-					goodSlaves.Add(1)
-				} // можно добавить исключение ноды из общего списка slave-нод
+				infos[i] = info
 			}(v)
 		}
 	}
-
 	wg.Wait()
-	res := goodSlaves.Load()
-	if res < int64(quorumN) { // если кол-во OK меньше quorumN+1, возвращаем ошибку
-		return fmt.Errorf("only %d slave-nodes are OK to continue, while quorum should be %d", res, quorumN)
+
+	nodes := make([]string, 0, len(ai.Slaves))
+	var goodSlaves int
+	for i, addr := range ai.Slaves {
+		info := infos[i]
+		if info == nil {
+			nodes = append(nodes, addr)
+			continue
+		}
+
+		var problem string
+		if info.Protocol != model.ProtocolVersion {
+			problem = fmt.Sprintf("speaks protocol %d, while the master speaks %d", info.Protocol, model.ProtocolVersion)
+		} else if missing := info.Missing(need); len(missing) != 0 {
+			problem = "doesn't support " + strings.Join(missing, ", ")
+		}
+		if problem == "" {
+			goodSlaves++
+			nodes = append(nodes, addr)
+			continue
+		}
+		if ai.Incompatible == model.IncompatibleFail {
+			return nil, fmt.Errorf("slave-node %q(version %q) %s", addr, info.Version, problem)
+		}
+		log.Printf("slave-node %q(version %q) %s, skipping it", addr, info.Version, problem)
 	}
 
-	return nil
+	if goodSlaves < quorumN { // если кол-во OK меньше quorumN+1, возвращаем ошибку
+		return nil, fmt.Errorf("only %d slave-nodes are OK to continue, while quorum should be %d", goodSlaves, quorumN)
+	}
+	return nodes, nil
 }
 
-func readInputConvertToTasks(ctx context.Context, src []string, gp model.GrepParam) ([]*model.MasterTask, error) {
+func readInputConvertToTasks(ctx context.Context, src []string, gp model.GrepParam, maxOpen int, fileErrs *fileErrors) ([]*model.MasterTask, error) {
 	var tasks []*model.MasterTask
-	var input []string
-	var err error
+
+	opts := reader.Options{
+		NullData:          gp.NullData,
+		Offsets:           gp.ByteOffset,
+		Decompress:        gp.Decompress,
+		Encoding:          gp.Encoding,
+		MaxLineSize:       gp.MaxLineSize,
+		TruncateLongLines: gp.LongLines == model.LongLinesTruncate,
+		Archives:          gp.Archives,
+		MaxMemberSize:     gp.MaxMemberSize,
+		MaxArchiveSize:    gp.MaxArchiveSize,
+		MaxArchiveMembers: gp.MaxArchiveMembers,
+	}
 
 	// преобразовать вход в задания
-	switch len(src) {
-	case 0: // читаем вход из stdIn
-		input, err = reader.ReadInput(os.Stdin, "")
+	if len(src) == 0 { // читаем вход из stdIn
+		input, err := reader.ReadInput(os.Stdin, "", opts)
 		if err != nil {
 			return nil, err
 		}
 		tCTX, cancel := context.WithCancel(ctx)
 		tasks = append(tasks, &model.MasterTask{
 			Task: model.TaskDTO{
-				TaskID: uuid.Generate().String(),
-				GP:     gp,
-				Input:  input,
+				TaskID:  uuid.Generate().String(),
+				GP:      gp,
+				Input:   input.Lines,
+				Binary:  reader.IsBinary(input.Lines),
+				Offsets: input.Offsets,
 			},
 			CTX:       tCTX,
 			CancelCTX: cancel,
 		})
-	case 1: // читаем из единственного файла
-		input, err = reader.ReadInput(os.Stdin, src[0])
-		if err != nil {
-			return nil, err
-		}
+		return tasks, nil
+	}
 
-		tCTX, cancel := context.WithCancel(ctx)
-		tasks = append(tasks, &model.MasterTask{
-			Task: model.TaskDTO{
-				TaskID:   uuid.Generate().String(),
-				GP:       gp,
-				Input:    input,
-				FileName: src[0],
-			},
-			CTX:       tCTX,
-			CancelCTX: cancel,
+	// читаем файлы параллельно, но не держим открытыми больше maxOpen файлов одновременно
+	if maxOpen < 1 {
+		maxOpen = 1
+	}
+	inputs := make([][]reader.ArchiveMember, len(src))
+	errs := make([]error, len(src))
+	sem := make(chan struct{}, maxOpen)
+	wg := sync.WaitGroup{}
+	for i, fname := range src {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			inputs[i], errs[i] = reader.ReadMembers(fname, opts)
 		})
-	default: // итерируемся по списку файлов
-		for _, fname := range src {
-			input, err = reader.ReadInput(os.Stdin, fname)
-			if err != nil {
-				return nil, err
+	}
+	wg.Wait()
+
+	for i, fname := range src {
+		if errs[i] != nil {
+			fileErrs.report((&reader.FileError{Path: fname, Err: errs[i]}).Error())
+			continue
+		}
+		// в архиве может быть несколько файлов - имя "архив:путь" печатаем, даже если операнд один
+		if len(inputs[i]) != 0 && inputs[i][0].Path != "" && !gp.NoFileName {
+			gp.PrintFileName = true
+		}
+	}
+
+	// каждый файл(и каждый файл внутри архива при --archives) - отдельное задание,
+	// порядок заданий совпадает с порядком файлов
+	for i, fname := range src {
+		for _, m := range inputs[i] {
+			binary := reader.IsBinary(m.Lines)
+			if binary && m.Path != "" && gp.BinaryFiles == model.BinaryWithoutMatch {
+				continue // -I: двоичный файл из архива совпадений не даст - не отправляем его вовсе
 			}
 
-			tCTX, cancel := context.WithTimeout(ctx, 1*time.Minute)
+			var tCTX context.Context
+			var cancel context.CancelFunc
+			switch len(src) {
+			case 1:
+				tCTX, cancel = context.WithCancel(ctx)
+			default:
+				tCTX, cancel = context.WithTimeout(ctx, 1*time.Minute)
+			}
 			tasks = append(tasks, &model.MasterTask{
 				Task: model.TaskDTO{
 					TaskID:   uuid.Generate().String(),
 					GP:       gp,
-					Input:    input,
-					FileName: fname,
+					Input:    m.Lines,
+					FileName: m.Name(fname),
+					Binary:   binary,
+					Offsets:  m.Offsets,
 				},
 				CTX:       tCTX,
 				CancelCTX: cancel,
@@ -161,20 +281,35 @@ func readInputConvertToTasks(ctx context.Context, src []string, gp model.GrepPar
 	return tasks, nil
 }
 
-func processTasks(ctx context.Context, nodes []string, tasks []*model.MasterTask, quorumN int) ([][]string, error) {
+func processTasks(ctx context.Context, client transport.Client, nodes []string, tasks []*model.MasterTask, quorumN int, noQuorum bool,
+	fileErrs *fileErrors) ([]qaggr.TaskResult, error) {
 	resCollect := make(chan model.SlaveResult)
 
-	// итерируемся по заданиям(их может быть несколько, если на вход подано несколько файлов)
-	client := http.Client{
-		Timeout: 5 * time.Second,
+	// сборщик результатов работает с таймаутом в 1 минуту; по его завершении обрываются и потоки заданий
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	// нода новее мастера выполнит задание, только если знает все нужные ему возможности
+	for _, task := range tasks {
+		task.Task.Features = task.Task.GP.RequiredFeatures()
 	}
+
 	wg := sync.WaitGroup{}
-	for i := range tasks {
-		task := tasks[i]
-		// итерируемся по всем slave-node адресам и отправляем задания
+	if streamer, ok := client.(transport.TaskStreamer); ok && len(tasks) > 1 {
+		// транспорт с потоками(gRPC): все задания ноде уходят по одному потоку
 		for _, nodeAddr := range nodes {
 			wg.Add(1)
-			go sendTaskToNode(task.CTX, &wg, &client, nodeAddr, task, resCollect)
+			go streamTasksToNode(ctx, &wg, streamer, nodeAddr, tasks, resCollect, fileErrs, noQuorum)
+		}
+	} else {
+		// итерируемся по заданиям(их может быть несколько, если на вход подано несколько файлов)
+		for i := range tasks {
+			task := tasks[i]
+			// итерируемся по всем slave-node адресам и отправляем задания
+			for _, nodeAddr := range nodes {
+				wg.Add(1)
+				go sendTaskToNode(task.CTX, &wg, client, nodeAddr, task, resCollect, fileErrs, noQuorum)
+			}
 		}
 	}
 
@@ -184,51 +319,85 @@ func processTasks(ctx context.Context, nodes []string, tasks []*model.MasterTask
 		close(resCollect)
 	}()
 
-	// запускаем сборщика результатов c таймаутом в 1 минуту на сбор
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
-	defer cancel()
-	return qaggr.CollectAggregateResults(ctx, resCollect, tasks, quorumN)
+	collect := func() ([]qaggr.TaskResult, []error, error) {
+		return qaggr.CollectTaskResults(ctx, resCollect, tasks, quorumN)
+	}
+	if noQuorum {
+		collect = func() ([]qaggr.TaskResult, []error, error) {
+			return qaggr.CollectNodeResults(ctx, resCollect, tasks)
+		}
+	}
+	// файл, по которому нет результата, - такая же ошибка, как и нечитаемый файл: код выхода 2
+	result, problems, err := collect()
+	fileErrs.reportAll(problems)
+	return result, err
 }
 
-func sendTaskToNode(ctx context.Context, wg *sync.WaitGroup, client *http.Client, na string, task *model.MasterTask, ch chan<- model.SlaveResult) {
+func sendTaskToNode(ctx context.Context, wg *sync.WaitGroup, client transport.Client, na string, task *model.MasterTask, ch chan<- model.SlaveResult,
+	fileErrs *fileErrors, noQuorum bool) {
 	defer wg.Done()
 
-	if !strings.Contains(na, "http://") {
-		na = "http://" + na
-	}
-
-	// сразу маршалим задание на отправку
-	raw, err := json.Marshal(task.Task)
+	result, err := client.SendTask(task.CTX, na, &task.Task)
 	if err != nil {
-		log.Printf("failed to MARSHAL task: %q", err.Error())
+		reportTaskError(na, task, err, fileErrs)
 		return
 	}
+	deliverResult(ctx, na, result, ch, fileErrs, noQuorum)
+}
 
-	body := bytes.NewReader(raw)
+// streamTasksToNode отправляет ноде все задания одним потоком; задание, которое уже отменено(например, кворум
+// по нему собран или достигнут -m), отменяется и на ноде, а его результат отбрасывается
+func streamTasksToNode(ctx context.Context, wg *sync.WaitGroup, streamer transport.TaskStreamer, na string, tasks []*model.MasterTask,
+	ch chan<- model.SlaveResult, fileErrs *fileErrors, noQuorum bool) {
+	defer wg.Done()
 
-	req, err := http.NewRequestWithContext(task.CTX, "POST", na+"/task", body)
-	if err != nil {
-		log.Printf("failed to GENERATE request to slave-node %q: %q", na, err.Error())
-		return
+	byID := make(map[string]*model.MasterTask, len(tasks))
+	for _, t := range tasks {
+		byID[t.Task.TaskID] = t
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("failed to SEND task to slave-node: %q", err.Error())
-		return
+	err := streamer.StreamTasks(ctx, na, tasks, func(result *model.SlaveResult) {
+		if t, ok := byID[result.TaskID]; ok {
+			deliverResult(t.CTX, na, result, ch, fileErrs, noQuorum)
+		}
+	}, func(taskID string, err error) {
+		if t, ok := byID[taskID]; ok {
+			reportTaskError(na, t, err, fileErrs)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("failed to process tasks on slave-node %q: %q", na, err.Error())
 	}
+}
 
-	defer resp.Body.Close()
-
-	var result model.SlaveResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Printf("failed to UNMARSHAL result from slave-node %q: %q", na, err.Error())
+// reportTaskError сообщает об ошибке задания на ноде: отказ ноды(4xx) - проблема с файлом задания, код выхода 2,
+// а сбой связи только пишется в лог - результат могут дать остальные ноды
+func reportTaskError(na string, task *model.MasterTask, err error, fileErrs *fileErrors) {
+	if task.CTX.Err() != nil { // задание отменено(кворум, -m, Ctrl+C) - ошибка ожидаема
+		return
+	}
+	if !transport.IsRejected(err) {
+		log.Printf("failed to process task on slave-node %q: %q", na, err.Error())
 		return
 	}
+	fileErrs.report(fmt.Sprintf("%s: slave-node %s: %v", task.Task.GP.InputName(task.Task.FileName), transport.HostPort(na), err))
+}
+
+// deliverResult передает результат ноды сборщику
+func deliverResult(ctx context.Context, na string, result *model.SlaveResult, ch chan<- model.SlaveResult, fileErrs *fileErrors, noQuorum bool) {
+	if result.Node == "" { // у ноды нет --name - называем ее по адресу
+		result.Node = transport.HostPort(na)
+	}
+	// проблемы с файлами --remote: при --no-quorum у каждой ноды свои файлы, поэтому сообщение помечается ее именем
+	for _, e := range result.Errors {
+		if noQuorum {
+			e = result.Node + ": " + e
+		}
+		fileErrs.report(e)
+	}
 
 	select {
-	case ch <- result:
+	case ch <- *result:
 	case <-ctx.Done():
 		return
 	}
@@ -0,0 +1,60 @@
+package appmode
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/printer"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/docker/distribution/uuid"
+)
+
+// runRemote - режим --remote: каждый операнд уходит slave-нодам отдельным заданием с путем/glob-шаблоном,
+// а файлы ищет и читает сама нода внутри своего --root. С --no-quorum результаты не сверяются между нодами,
+// и для работы достаточно одной доступной ноды
+func runRemote(ctx context.Context, ai *model.AppInit, client transport.Client, fileErrs *fileErrors) int {
+	quorumN := ai.Quorum
+	if ai.NoQuorum {
+		quorumN = 1
+	}
+	var err error
+	if ai.Slaves, err = checkSlavesHealth(ctx, client, ai, quorumN); err != nil {
+		log.Printf("Failed to start grepping: %v", err)
+		return ExitTrouble
+	}
+
+	tasks := make([]*model.MasterTask, 0, len(ai.SearchParam.Source))
+	for _, pattern := range ai.SearchParam.Source {
+		tCTX, cancel := context.WithTimeout(ctx, 1*time.Minute)
+		tasks = append(tasks, &model.MasterTask{
+			Task: model.TaskDTO{
+				TaskID:   uuid.Generate().String(),
+				GP:       ai.SearchParam,
+				Input:    []string{},
+				FileName: pattern,
+				Paths:    []string{pattern},
+			},
+			CTX:       tCTX,
+			CancelCTX: cancel,
+		})
+	}
+	defer func() {
+		for _, t := range tasks {
+			t.CancelCTX()
+		}
+	}()
+
+	result, err := processTasks(ctx, client, ai.Slaves, tasks, ai.Quorum, ai.NoQuorum, fileErrs)
+	if err != nil {
+		log.Printf("Failed to grep: %v", err)
+		return ExitTrouble
+	}
+	if err := printResults(printer.New(os.Stdout, ai.SearchParam.Color), result); err != nil {
+		log.Printf("Failed to print result: %v", err)
+		return ExitTrouble
+	}
+	return fileErrs.exitCode(anySelected(&ai.SearchParam, result))
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
@@ -12,14 +13,38 @@ import (
 	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
 )
 
-func RunSlave(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) {
+// RunSlave работает до сигнала остановки и возвращает ExitTrouble, если сервер не удалось запустить
+func RunSlave(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) int {
 	// получить экземпляр сервера
-	p := processor.Processor{}
-	srv := transport.NewSlaveServer(ai.Address, p)
+	p := processor.Processor{Root: ai.Root, Node: ai.NodeName}
+	tlsConf, err := transport.ServerTLSConfig(ai.TLS)
+	if err != nil {
+		log.Printf("Failed to launch slave-node: %v", err)
+		return ExitTrouble
+	}
+	opts := transport.ServerOptions{
+		TLS:                 tlsConf,
+		MaxDecompressedSize: ai.MaxUnzipped,
+		Metrics:             &transport.Metrics{},
+		Limits:              transport.Limits{MaxBodySize: ai.MaxBody, MaxLines: ai.MaxLines, MaxLineSize: ai.MaxLineLen},
+	}
+	if ai.AuthTokens != "" {
+		if opts.Auth, err = transport.LoadTokenStore(ai.AuthTokens); err != nil {
+			log.Printf("Failed to launch slave-node: %v", err)
+			return ExitTrouble
+		}
+	}
+	srv, err := transport.NewServer(ai.Transport, ai.Address, p, opts)
+	if err != nil {
+		log.Printf("Failed to launch slave-node: %v", err)
+		return ExitTrouble
+	}
 
 	// запуск сервера
+	var failed atomic.Bool
 	go func() {
-		log.Printf("Slave running on %s", srv.Addr)
+		log.Printf("Slave running on :%s(%s, TLS: %t, client certificates: %t, tokens: %t)",
+			ai.Address, ai.Transport, tlsConf != nil, ai.TLS.CAFile != "", opts.Auth != nil)
 		err := srv.ListenAndServe()
 		if err != nil {
 			switch {
@@ -27,6 +52,7 @@ func RunSlave(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) {
 				log.Println("Server gracefully stopping...")
 			default:
 				log.Printf("Server stopped: %v", err)
+				failed.Store(true)
 				stop()
 			}
 		}
@@ -42,4 +68,9 @@ func RunSlave(ctx context.Context, stop context.CancelFunc, ai *model.AppInit) {
 	} else {
 		log.Printf("Slave-node %q server is closed.", ai.Address)
 	}
+	log.Printf("Slave-node %q transfer: %s", ai.Address, opts.Metrics)
+	if failed.Load() {
+		return ExitTrouble
+	}
+	return 0
 }
@@ -0,0 +1,116 @@
+package appmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/qaggr"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+)
+
+// Коды выхода мастера - как у GNU grep
+const (
+	ExitSelected   = 0 // выбрана хотя бы одна строка
+	ExitNoSelected = 1 // ни одной строки не выбрано
+	ExitTrouble    = 2 // была ошибка - в том числе с отдельным файлом, даже если в остальных что-то нашлось
+)
+
+// fileErrors печатает проблемы с отдельными входными файлами в stderr в формате GNU grep("mygrep: путь: причина")
+// и запоминает, что они были, - для кода выхода. При -s сообщения не печатаются, но код выхода все равно 2.
+// Одинаковые сообщения(например, одна и та же ошибка от нескольких slave-нод) печатаются один раз.
+// Предупреждения(*reader.FileWarning) печатаются так же, но на код выхода не влияют
+type fileErrors struct {
+	silent bool
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	failed bool
+}
+
+func newFileErrors(silent bool) *fileErrors {
+	return &fileErrors{
+		silent: silent,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+func (fe *fileErrors) report(msg string) {
+	fe.print(msg, true)
+}
+
+func (fe *fileErrors) warn(msg string) {
+	fe.print(msg, false)
+}
+
+func (fe *fileErrors) print(msg string, failed bool) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.failed = fe.failed || failed
+	if _, ok := fe.seen[msg]; ok {
+		return
+	}
+	fe.seen[msg] = struct{}{}
+	if !fe.silent {
+		fmt.Fprintf(os.Stderr, "mygrep: %s\n", msg)
+	}
+}
+
+func (fe *fileErrors) reportAll(problems []error) {
+	for _, p := range problems {
+		var fw *reader.FileWarning
+		if errors.As(p, &fw) {
+			fe.warn(p.Error())
+			continue
+		}
+		fe.report(p.Error())
+	}
+}
+
+func (fe *fileErrors) any() bool {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	return fe.failed
+}
+
+// exitCode - итоговый код выхода по найденному и по ошибкам с файлами
+func (fe *fileErrors) exitCode(selected bool) int {
+	switch {
+	case fe.any():
+		return ExitTrouble
+	case selected:
+		return ExitSelected
+	default:
+		return ExitNoSelected
+	}
+}
+
+// anySelected сообщает, выбрана ли хотя бы одна строка: при -L - есть ли файлы с совпадениями(о них сообщают
+// slave-ноды), при -c - есть ли ненулевой счетчик, в остальных режимах - есть ли вывод(контекст и
+// "Binary file X matches" бывают только при выбранных строках)
+func anySelected(gp *model.GrepParam, result []qaggr.TaskResult) bool {
+	for _, v := range result {
+		if gp.FilesWithoutMatch { // в выводе -L только файлы без совпадений
+			if v.Matched > 0 {
+				return true
+			}
+			continue
+		}
+		if !gp.CountFound {
+			if len(v.Output) != 0 {
+				return true
+			}
+			continue
+		}
+		for _, line := range v.Output {
+			n, err := strconv.Atoi(line[strings.LastIndexAny(line, ":\x00")+1:])
+			if err == nil && n > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package appmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/qaggr"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnySelected(t *testing.T) {
+	cases := []struct {
+		name    string
+		gp      model.GrepParam
+		result  []qaggr.TaskResult
+		wantRes bool
+	}{
+		{
+			name:    "Positive - selected lines",
+			result:  []qaggr.TaskResult{{Output: []string{}}, {Output: []string{"a.log:line"}}},
+			wantRes: true,
+		},
+		{
+			name:    "Negative - no output",
+			result:  []qaggr.TaskResult{{Output: []string{}}, {Output: nil}},
+			wantRes: false,
+		},
+		{
+			name:    "Positive - -c with a non-zero count",
+			gp:      model.GrepParam{CountFound: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a.log:0"}}, {Output: []string{"b.log:3"}}},
+			wantRes: true,
+		},
+		{
+			name:    "Negative - -c with zero counts only",
+			gp:      model.GrepParam{CountFound: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a.log:0"}}, {Output: []string{"0"}}},
+			wantRes: false,
+		},
+		{
+			name:    "Positive - -c -Z, name with ':' and a non-zero count",
+			gp:      model.GrepParam{CountFound: true, NullName: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a:10.log\x002"}}},
+			wantRes: true,
+		},
+		{
+			name:    "Negative - -c -Z, name ending with a number and a zero count",
+			gp:      model.GrepParam{CountFound: true, NullName: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a:10\x000"}}},
+			wantRes: false,
+		},
+		{
+			name:    "Positive - -L, a file with matches is not listed",
+			gp:      model.GrepParam{FilesWithoutMatch: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a.log"}}, {Output: []string{}, Matched: 1}},
+			wantRes: true,
+		},
+		{
+			name:    "Negative - -L, listed files only",
+			gp:      model.GrepParam{FilesWithoutMatch: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a.log"}}, {Output: []string{"b.log"}}},
+			wantRes: false,
+		},
+		{
+			name:    "Positive - -l",
+			gp:      model.GrepParam{FilesWithMatch: true},
+			result:  []qaggr.TaskResult{{Output: []string{"a.log"}}},
+			wantRes: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantRes, anySelected(&tt.gp, tt.result))
+		})
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	cases := []struct {
+		name       string
+		silent     bool
+		problems   []error
+		selected   bool
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:     "Positive - selected lines without errors",
+			selected: true,
+			wantCode: ExitSelected,
+		},
+		{
+			name:     "Positive - nothing selected",
+			wantCode: ExitNoSelected,
+		},
+		{
+			name: "Negative - same error from several slave-nodes is printed once",
+			problems: []error{
+				&reader.FileError{Path: "a.log", Err: os.ErrNotExist},
+				fmt.Errorf("b.log: %w", qaggr.ErrNoQuorum),
+				&reader.FileError{Path: "a.log", Err: os.ErrNotExist},
+			},
+			selected:   true,
+			wantCode:   ExitTrouble,
+			wantStderr: "mygrep: a.log: No such file or directory\nmygrep: b.log: " + qaggr.ErrNoQuorum.Error() + "\n",
+		},
+		{
+			name:     "Negative - -s hides messages, but the exit code is still 2",
+			silent:   true,
+			problems: []error{&reader.FileError{Path: "a.log", Err: os.ErrPermission}},
+			selected: true,
+			wantCode: ExitTrouble,
+		},
+		{
+			name:       "Positive - warning doesn't change the exit code",
+			problems:   []error{&reader.FileWarning{Path: "dir/up", Msg: "recursive directory loop"}},
+			wantCode:   ExitNoSelected,
+			wantStderr: "mygrep: dir/up: warning: recursive directory loop\n",
+		},
+		{
+			name:     "Positive - -s hides warnings too",
+			silent:   true,
+			problems: []error{&reader.FileWarning{Path: "dir/up", Msg: "recursive directory loop"}},
+			selected: true,
+			wantCode: ExitSelected,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := captureStderr(t, func() {
+				fe := newFileErrors(tt.silent)
+				fe.reportAll(tt.problems)
+				require.Equal(t, tt.wantCode, fe.exitCode(tt.selected))
+			})
+			require.Equal(t, tt.wantStderr, stderr)
+		})
+	}
+}
+
+// captureStderr возвращает то, что fn напечатала в os.Stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	orig := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = orig }()
+
+	fn()
+	require.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
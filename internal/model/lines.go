@@ -0,0 +1,113 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// rawLines - строки входа задания и вывода результата в JSON. encoding/json заменяет невалидный UTF-8
+// на U+FFFD, поэтому, если хоть одна строка не UTF-8, весь список передается в base64:
+//
+//	{"base64": ["4pyTIGE=", "/w=="]}
+//
+// иначе - обычным массивом строк, который понимают и slave-ноды старых версий
+type rawLines []string
+
+type base64Lines struct {
+	Base64 [][]byte `json:"base64"`
+}
+
+func (l rawLines) MarshalJSON() ([]byte, error) {
+	for _, s := range l {
+		if utf8.ValidString(s) {
+			continue
+		}
+		enc := base64Lines{Base64: make([][]byte, len(l))}
+		for i, s := range l {
+			enc.Base64[i] = []byte(s)
+		}
+		return json.Marshal(enc)
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *rawLines) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return json.Unmarshal(data, (*[]string)(l))
+	}
+	var dec base64Lines
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	*l = make(rawLines, len(dec.Base64))
+	for i, b := range dec.Base64 {
+		(*l)[i] = string(b)
+	}
+	return nil
+}
+
+// MarshalJSON/UnmarshalJSON у DTO подменяют только Input/Output, остальные поля кодируются как обычно
+
+func (t TaskDTO) MarshalJSON() ([]byte, error) {
+	type plain TaskDTO
+	return json.Marshal(struct {
+		plain
+		Input rawLines `json:"input"`
+	}{plain(t), rawLines(t.Input)})
+}
+
+func (t *TaskDTO) UnmarshalJSON(data []byte) error {
+	type plain TaskDTO
+	aux := struct {
+		*plain
+		Input rawLines `json:"input"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.Input = aux.Input
+	return nil
+}
+
+func (t SlaveTask) MarshalJSON() ([]byte, error) {
+	type plain SlaveTask
+	return json.Marshal(struct {
+		plain
+		Input rawLines `json:"input"`
+	}{plain(t), rawLines(t.Input)})
+}
+
+func (t *SlaveTask) UnmarshalJSON(data []byte) error {
+	type plain SlaveTask
+	aux := struct {
+		*plain
+		Input rawLines `json:"input"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.Input = aux.Input
+	return nil
+}
+
+func (r SlaveResult) MarshalJSON() ([]byte, error) {
+	type plain SlaveResult
+	return json.Marshal(struct {
+		plain
+		Output rawLines `json:"output"`
+	}{plain(r), rawLines(r.Output)})
+}
+
+func (r *SlaveResult) UnmarshalJSON(data []byte) error {
+	type plain SlaveResult
+	aux := struct {
+		*plain
+		Output rawLines `json:"output"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Output = aux.Output
+	return nil
+}
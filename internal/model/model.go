@@ -4,6 +4,11 @@ package model
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
 )
 
 type AppMode string
@@ -13,12 +18,64 @@ const (
 	ModeSlave  = AppMode("slave")
 )
 
+// значения --transport - протокол между мастером и slave-нодами
+const (
+	TransportHTTP = "http" // JSON по HTTP: GET /ping, POST /task
+	TransportGRPC = "grpc" // gRPC-сервис Grep: Health, Task и потоковый TaskStream
+)
+
+// значения --incompatible-nodes - что делать со slave-нодами без возможностей, нужных запросу
+const (
+	IncompatibleSkip = "skip" // не отправлять им задания
+	IncompatibleFail = "fail" // не начинать поиск
+)
+
+// значения --compress - сжатие заданий и результатов между мастером и slave-нодами
+const (
+	CompressGzip = "gzip"
+	CompressNone = "none"
+)
+
 type AppInit struct {
-	Mode        AppMode
-	Address     string
-	Slaves      NodesList
-	Quorum      int
-	SearchParam GrepParam
+	Mode         AppMode
+	Address      string
+	Slaves       NodesList
+	Quorum       int
+	MaxOpen      int           // сколько файлов мастер может держать открытыми одновременно при чтении входа
+	FilesFrom    string        // --files-from/--files0-from — список файлов для поиска прочитан из файла(или stdin, если "-")
+	NoQuorum     bool          // --no-quorum — выводить результаты каждой slave-ноды отдельно, без голосования(только с --remote)
+	Root         string        // --root — каталог slave-ноды, внутри которого ищутся файлы заданий --remote
+	NodeName     string        // --name — имя slave-ноды в результатах; по умолчанию мастер подставляет ее адрес
+	PollEvery    time.Duration // --follow-interval — как часто мастер проверяет файлы в режиме --follow
+	Transport    string        // --transport — TransportHTTP или TransportGRPC; у мастера и его slave-нод должен совпадать
+	TLS          TLSParam
+	AuthTokens   string // --auth-tokens — файл токенов slave-ноды; пусто — задания принимаются без токена
+	AuthToken    string // bearer-токен мастера из --auth-token-file или переменной окружения AuthTokenEnv
+	Compress     string // --compress — CompressGzip или CompressNone(только мастер: slave-нода принимает оба варианта)
+	MaxUnzipped  int64  // --max-decompressed-size — ограничение задания(slave-нода) или результата(мастер) после распаковки
+	Stats        bool   // --transfer-stats — мастер выводит в stderr, сколько байт передано со сжатием и без
+	MaxBody      int64  // --max-body-size — slave-нода не принимает задания больше N байт, как они пришли по сети
+	MaxLines     int    // --max-task-lines — slave-нода не принимает задания больше чем из N строк
+	MaxLineLen   int    // --max-task-line-size — slave-нода не принимает задания со строками длиннее N байт
+	Incompatible string // --incompatible-nodes — IncompatibleSkip или IncompatibleFail
+	SearchParam  GrepParam
+}
+
+// AuthTokenEnv - переменная окружения с токеном мастера, если --auth-token-file не задан
+const AuthTokenEnv = "MYGREP_AUTH_TOKEN"
+
+// TLSParam - файлы TLS в формате PEM. У slave-ноды это ее сертификат и ключ, а CAFile включает проверку
+// клиентских сертификатов(mTLS); у мастера - клиентский сертификат для mTLS и CA, которым подписаны сертификаты
+// slave-нод(вместо системных корневых сертификатов)
+type TLSParam struct {
+	CertFile string // --tls-cert
+	KeyFile  string // --tls-key
+	CAFile   string // --tls-ca
+}
+
+// Enabled сообщает, задан ли хотя бы один параметр TLS
+func (tp TLSParam) Enabled() bool {
+	return tp.CertFile != "" || tp.KeyFile != "" || tp.CAFile != ""
 }
 
 // NodesList - для чтения списка slave-nodes в виде слайса из OS.args
@@ -43,17 +100,274 @@ func (n *NodesList) Set(value string) error { // в Set сразу избавл
 
 // GrepParam - хранит в себе все возможные флаги и параметры запуска grep
 type GrepParam struct {
-	CtxAfter      int      `json:"ctx_after"`                  // A n — вывести N строк после каждой найденной строки
-	CtxBefore     int      `json:"ctx_before"`                 // B n — вывести N строк до каждой найденной строки
-	CtxCircle     int      `json:"-"`                          // C N — вывести N строк контекста вокруг найденной строки (включает и до, и после; эквивалентно -A N -B N)
-	CountFound    bool     `json:"count_found"`                // c — выводить только число совпавших с шаблоном строк,  -n/-A/-B/-C при этом игнорируются
-	IgnoreCase    bool     `json:"ignore_case"`                // i — игнорировать регистр
-	InvertResult  bool     `json:"invert_result"`              // v — инвертировать фильтр: выводить строки, не содержащие шаблон
-	ExactMatch    bool     `json:"exact_match"`                // F — выполнять точное совпадение подстроки - вето на регулярку
-	EnumLine      bool     `json:"enum_line"`                  // n — выводить номер строки перед каждой найденной строкой.
-	Source        []string `json:"-"`                          // Имя/имена файлов для чтения данных
-	Pattern       string   `json:"pattern" binding:"required"` // raw Regexp или строка для поиска
-	PrintFileName bool     `json:"print_filename"`             // used to print filename prefix if there are >1 files to process
+	CtxAfter          int       `json:"ctx_after"`                     // A n — вывести N строк после каждой найденной строки
+	CtxBefore         int       `json:"ctx_before"`                    // B n — вывести N строк до каждой найденной строки
+	CtxCircle         int       `json:"-"`                             // C N — вывести N строк контекста вокруг найденной строки (включает и до, и после; эквивалентно -A N -B N)
+	CountFound        bool      `json:"count_found"`                   // c — выводить только число совпавших с шаблоном строк,  -n/-A/-B/-C при этом игнорируются
+	IgnoreCase        bool      `json:"ignore_case"`                   // i — игнорировать регистр
+	InvertResult      bool      `json:"invert_result"`                 // v — инвертировать фильтр: выводить строки, не содержащие шаблон
+	ExactMatch        bool      `json:"exact_match"`                   // F — выполнять точное совпадение подстроки - вето на регулярку
+	EnumLine          bool      `json:"enum_line"`                     // n — выводить номер строки перед каждой найденной строкой.
+	Source            []string  `json:"-"`                             // Имя/имена файлов для чтения данных
+	Pattern           string    `json:"pattern" binding:"required"`    // raw Regexp или строка для поиска
+	PrintFileName     bool      `json:"print_filename"`                // used to print filename prefix if there are >1 files to process
+	MaxCount          *int      `json:"max_count,omitempty"`           // m NUM — остановиться после NUM выбранных строк; nil — без ограничения
+	FilesWithMatch    bool      `json:"files_with_match"`              // l — выводить только имена файлов, в которых есть выбранные строки
+	FilesWithoutMatch bool      `json:"files_without_match"`           // L — выводить только имена файлов, в которых нет выбранных строк
+	Walk              WalkParam `json:"-"`                             // r/R, --include/--exclude/--exclude-dir — обход каталогов на стороне мастера
+	Color             bool      `json:"color"`                         // --color — slave-нода размечает совпадения в строках, мастер их подсвечивает
+	BinaryFiles       string    `json:"binary_files,omitempty"`        // --binary-files/-a/-I — как обрабатывать двоичные файлы; пусто — как BinaryMatches
+	NullData          bool      `json:"null_data"`                     // z — записи входа и вывода разделяются NUL-байтом, а не переводом строки
+	NullName          bool      `json:"null_name"`                     // Z — после имени файла выводить NUL-байт вместо ':'
+	ByteOffset        bool      `json:"byte_offset"`                   // b — выводить байтовое смещение начала строки от начала входа
+	Column            bool      `json:"column"`                        // --column — выводить номер колонки(в байтах, с 1) первого совпадения в выбранной строке
+	NoFileName        bool      `json:"-"`                             // h — не выводить имя файла, даже если файлов несколько
+	Label             string    `json:"label,omitempty"`               // --label — имя, под которым выводится stdin
+	GroupSeparator    *string   `json:"group_separator,omitempty"`     // --group-separator — разделитель групп контекста; nil — "--"
+	NoGroupSeparator  bool      `json:"no_group_separator,omitempty"`  // --no-group-separator — не выводить разделитель групп контекста
+	Heading           bool      `json:"heading,omitempty"`             // --heading — выводить имя файла один раз перед его строками, а не в каждой строке
+	Decompress        bool      `json:"decompress,omitempty"`          // --decompress — распаковывать сжатый вход при чтении(мастером или slave-нодой при --remote)
+	MaxLineSize       int       `json:"max_line_size,omitempty"`       // --max-line-size — не читать строки длиннее N байт
+	LongLines         string    `json:"long_lines,omitempty"`          // --long-lines — что делать со строкой длиннее --max-line-size: LongLinesError или LongLinesTruncate
+	Encoding          string    `json:"encoding,omitempty"`            // --encoding — кодировка входа, вход перекодируется в UTF-8 при чтении; пусто — "auto"(только по BOM)
+	Archives          bool      `json:"archives,omitempty"`            // --archives — искать в каждом файле tar/zip архива как в отдельном файле "архив:путь"
+	MaxMemberSize     int64     `json:"max_member_size,omitempty"`     // --max-member-size — файлы архива больше N байт пропускаются
+	MaxArchiveSize    int64     `json:"max_archive_size,omitempty"`    // --max-archive-size — архив, файлы которого вместе больше N байт, не читается
+	MaxArchiveMembers int       `json:"max_archive_members,omitempty"` // --max-archive-members — архив, в котором больше N файлов, не читается
+	NoMessages        bool      `json:"-"`                             // -s — не выводить сообщения об отсутствующих и нечитаемых файлах
+	Follow            bool      `json:"-"`                             // --follow — мастер следит за дописыванием в файлы и отправляет новые строки отдельными заданиями
+	Remote            bool      `json:"remote,omitempty"`              // --remote — операнды это пути/glob-шаблоны файлов на дисках slave-нод, мастер их не читает
+}
+
+// NeedsMeta сообщает, нужна ли мастеру разметка строк результата: для обрезки по -m,
+// подсветки --color и для того, чтобы отличать записи от разделителей групп при -z и
+// при замене разделителей по --group-separator/--no-group-separator и при --remote(разделители не помечаются именем ноды)
+func (gp *GrepParam) NeedsMeta() bool {
+	return gp.MaxCount != nil || gp.Color || gp.NullData || gp.GroupSeparator != nil || gp.NoGroupSeparator || gp.Remote
+}
+
+// DefaultGroupSeparator - разделитель групп контекста по умолчанию, как в GNU grep
+const DefaultGroupSeparator = "--"
+
+// GroupSep возвращает разделитель групп контекста и false, если разделитель выводить не нужно
+func (gp *GrepParam) GroupSep() (string, bool) {
+	switch {
+	case gp.NoGroupSeparator:
+		return "", false
+	case gp.GroupSeparator != nil:
+		return *gp.GroupSeparator, true
+	default:
+		return DefaultGroupSeparator, true
+	}
+}
+
+// FieldError - недопустимое значение поля задания; Field - путь поля в JSON задания
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (fe *FieldError) Error() string {
+	return fe.Field + ": " + fe.Reason
+}
+
+// Validate проверяет параметры поиска, пришедшие в задании: парсер мастера не пропускает такие значения,
+// а slave-нода не должна полагаться на то, что задание прислал именно он. Ошибка - *FieldError
+func (gp *GrepParam) Validate() error {
+	switch {
+	case gp.CtxAfter < 0:
+		return &FieldError{Field: "grep_param.ctx_after", Reason: "must not be negative"}
+	case gp.CtxBefore < 0:
+		return &FieldError{Field: "grep_param.ctx_before", Reason: "must not be negative"}
+	case gp.MaxCount != nil && *gp.MaxCount < 0:
+		return &FieldError{Field: "grep_param.max_count", Reason: "must not be negative"}
+	case gp.MaxLineSize < 0:
+		return &FieldError{Field: "grep_param.max_line_size", Reason: "must not be negative"}
+	case gp.MaxMemberSize < 0:
+		return &FieldError{Field: "grep_param.max_member_size", Reason: "must not be negative"}
+	case gp.MaxArchiveSize < 0:
+		return &FieldError{Field: "grep_param.max_archive_size", Reason: "must not be negative"}
+	case gp.MaxArchiveMembers < 0:
+		return &FieldError{Field: "grep_param.max_archive_members", Reason: "must not be negative"}
+	case gp.FilesWithMatch && gp.FilesWithoutMatch:
+		return &FieldError{Field: "grep_param.files_without_match", Reason: "can't be combined with files_with_match"}
+	}
+
+	switch gp.BinaryFiles {
+	case "", BinaryMatches, BinaryText, BinaryWithoutMatch:
+	default:
+		return &FieldError{Field: "grep_param.binary_files", Reason: fmt.Sprintf("unknown value %q", gp.BinaryFiles)}
+	}
+	switch gp.LongLines {
+	case "", LongLinesError, LongLinesTruncate:
+	default:
+		return &FieldError{Field: "grep_param.long_lines", Reason: fmt.Sprintf("unknown value %q", gp.LongLines)}
+	}
+
+	// -F ищет подстроку, остальные паттерны должны компилироваться так же, как их скомпилирует processor
+	if !gp.ExactMatch {
+		if _, err := regexp.Compile(gp.Pattern); err != nil {
+			return &FieldError{Field: "grep_param.pattern", Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// ProtocolVersion - версия формата заданий и результатов. Новые параметры поиска добавляются возможностями
+// (Feature*) без смены версии; версия меняется, только если старая slave-нода не сможет разобрать задание
+const ProtocolVersion = 1
+
+// Возможности slave-ноды: параметры поиска, которые появились после первой версии протокола. Нода, которая
+// о них не знает, молча их проигнорирует и вернет неверный результат, поэтому мастер отправляет задания
+// только нодам, поддерживающим все нужные запросу возможности
+const (
+	FeatureMaxCount       = "max-count"       // -m
+	FeatureListFiles      = "list-files"      // -l/-L
+	FeatureColor          = "color"           // --color: разметка совпадений
+	FeatureBinaryFiles    = "binary-files"    // -a/-I/--binary-files=text|without-match
+	FeatureNullData       = "null-data"       // -z
+	FeatureNullName       = "null-name"       // -Z
+	FeatureByteOffset     = "byte-offset"     // -b
+	FeatureColumn         = "column"          // --column
+	FeatureLabel          = "label"           // --label
+	FeatureGroupSeparator = "group-separator" // --group-separator/--no-group-separator: разметка разделителей
+	FeatureHeading        = "heading"         // --heading
+	FeatureRemote         = "remote"          // --remote
+	FeatureArchives       = "archives"        // --remote с --archives: архивы читает нода
+	FeatureEncoding       = "encoding"        // --remote с --encoding: перекодирует нода
+	FeatureFollowContext  = "follow-context"  // --follow с -A/-B: контекст на стыке заданий(ContextCarry)
+)
+
+// Возможности транспорта, а не поиска: заданиям они не требуются, мастер пользуется ими только у нод, которые
+// сообщили о них в /ping
+const (
+	FeatureGzip         = "gzip"          // нода принимает задания, сжатые gzip; старые ноды на сжатое задание отвечают 400
+	FeatureStreamCancel = "stream-cancel" // нода отменяет задание gRPC-потока по сообщению мастера
+)
+
+// Features - возможности этой сборки, о которых slave-нода сообщает в /ping
+var Features = []string{
+	FeatureMaxCount, FeatureListFiles, FeatureColor, FeatureBinaryFiles, FeatureNullData, FeatureNullName,
+	FeatureByteOffset, FeatureColumn, FeatureLabel, FeatureGroupSeparator, FeatureHeading, FeatureRemote, FeatureArchives, FeatureEncoding,
+	FeatureFollowContext, FeatureGzip, FeatureStreamCancel,
+}
+
+// RequiredFeatures возвращает возможности slave-ноды, без которых запрос выполнится неверно
+func (gp *GrepParam) RequiredFeatures() []string {
+	var need []string
+	for _, f := range []struct {
+		used    bool
+		feature string
+	}{
+		{gp.MaxCount != nil, FeatureMaxCount},
+		{gp.FilesWithMatch || gp.FilesWithoutMatch, FeatureListFiles},
+		{gp.Color, FeatureColor},
+		{gp.BinaryFiles == BinaryText || gp.BinaryFiles == BinaryWithoutMatch, FeatureBinaryFiles},
+		{gp.NullData, FeatureNullData},
+		{gp.NullName, FeatureNullName},
+		{gp.ByteOffset, FeatureByteOffset},
+		{gp.Column, FeatureColumn},
+		{gp.Label != "", FeatureLabel},
+		{gp.GroupSeparator != nil || gp.NoGroupSeparator, FeatureGroupSeparator},
+		{gp.Heading, FeatureHeading},
+		{gp.Remote, FeatureRemote},
+		// без --remote вход читает и перекодирует мастер
+		{gp.Remote && gp.Archives, FeatureArchives},
+		{gp.Remote && gp.Encoding != "" && gp.Encoding != "auto", FeatureEncoding},
+		{gp.CarriesContext(), FeatureFollowContext},
+	} {
+		if f.used {
+			need = append(need, f.feature)
+		}
+	}
+	return need
+}
+
+// CarriesContext сообщает, что задания --follow продолжают вывод контекста -A/-B предыдущего задания того же файла
+func (gp *GrepParam) CarriesContext() bool {
+	return gp.Follow && (gp.CtxAfter > 0 || gp.CtxBefore > 0) && !gp.CountFound && !gp.FilesWithMatch && !gp.FilesWithoutMatch
+}
+
+// NodeInfo - ответ slave-ноды на /ping(gRPC Health)
+type NodeInfo struct {
+	Protocol int      `json:"protocol"`
+	Version  string   `json:"version,omitempty"` // версия сборки slave-ноды - только для логов
+	Features []string `json:"features,omitempty"`
+}
+
+// Missing возвращает возможности из need, которых у ноды нет
+func (ni *NodeInfo) Missing(need []string) []string {
+	var missing []string
+	for _, f := range need {
+		if !slices.Contains(ni.Features, f) {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// значения --long-lines
+const (
+	LongLinesError    = "error"    // прервать чтение входа с ошибкой
+	LongLinesTruncate = "truncate" // обрезать строку и пометить ее
+)
+
+// StdinName - имя, под которым выводится stdin, если --label не задан
+const StdinName = "(standard input)"
+
+// InputName - имя входа для вывода: у stdin(пустое имя файла) это --label или StdinName
+func (gp *GrepParam) InputName(fileName string) string {
+	switch {
+	case fileName != "":
+		return fileName
+	case gp.Label != "":
+		return gp.Label
+	default:
+		return StdinName
+	}
+}
+
+// CountLine - строка вывода -c для входа name: "[имя:]счетчик", при -Z после имени вместо ':' идет NUL-байт
+func (gp *GrepParam) CountLine(name string, n int) string {
+	if !gp.PrintFileName {
+		return strconv.Itoa(n)
+	}
+	if gp.NullName {
+		return name + "\x00" + strconv.Itoa(n)
+	}
+	return name + ":" + strconv.Itoa(n)
+}
+
+// значения --binary-files, как у GNU grep
+const (
+	BinaryMatches      = "binary"        // вместо строк печатать "Binary file X matches"
+	BinaryText         = "text"          // -a — обрабатывать двоичный файл как текст
+	BinaryWithoutMatch = "without-match" // -I — считать, что в двоичном файле нет совпадений
+)
+
+// WalkParam - параметры рекурсивного обхода каталогов и фильтрации файлов по имени
+type WalkParam struct {
+	Recursive      bool     // r — рекурсивно обходить каталоги, указанные в аргументах
+	FollowSymlinks bool     // R — как r, но переходить по всем символическим ссылкам, а не только по указанным в аргументах
+	Include        GlobList // --include — искать только в файлах, имя которых подходит под один из glob
+	Exclude        GlobList // --exclude — пропускать файлы, имя которых подходит под один из glob
+	ExcludeDir     GlobList // --exclude-dir — не заходить в каталоги, имя которых подходит под один из glob
+	KeepMissing    bool     // --follow — не отбрасывать отсутствующие файлы: они могут появиться позже
+}
+
+// GlobList - для чтения повторяющихся glob-флагов из OS.args
+type GlobList []string
+
+func (g *GlobList) String() string {
+	return fmt.Sprint(*g)
+}
+
+func (g *GlobList) Set(value string) error {
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("incorrect glob %q: %v", value, err)
+	}
+	*g = append(*g, value)
+	return nil
 }
 
 type MasterTask struct {
@@ -62,20 +376,71 @@ type MasterTask struct {
 	CancelCTX context.CancelFunc
 }
 type TaskDTO struct {
-	TaskID   string    `json:"tid" binding:"required"`
-	GP       GrepParam `json:"grep_param" binding:"required"`
-	Input    []string  `json:"input" binding:"required"`
-	FileName string    `json:"file_name,omitempty"`
+	TaskID     string        `json:"tid" binding:"required"`
+	GP         GrepParam     `json:"grep_param" binding:"required"`
+	Input      []string      `json:"input" binding:"required"`
+	FileName   string        `json:"file_name,omitempty"`
+	Binary     bool          `json:"binary,omitempty"`      // мастер распознал вход как двоичный(NUL-байты или невалидный UTF-8)
+	Offsets    []int64       `json:"offsets,omitempty"`     // смещения строк Input от начала задания - передаются только при -b
+	BaseOffset int64         `json:"base_offset,omitempty"` // смещение начала задания во входе - для заданий с середины файла
+	BaseLine   int           `json:"base_line,omitempty"`   // кол-во строк входа перед началом задания
+	Paths      []string      `json:"paths,omitempty"`       // --remote — пути/glob-шаблоны файлов на диске slave-ноды вместо Input
+	Features   []string      `json:"features,omitempty"`    // возможности, без которых задание не выполнить правильно(GrepParam.RequiredFeatures)
+	Carry      *ContextCarry `json:"carry,omitempty"`       // --follow с -A/-B: вывод контекста после предыдущего задания того же файла
 }
 
 type SlaveTask struct {
-	TaskID   string    `json:"tid" binding:"required"`
-	GP       GrepParam `json:"grep_param" binding:"required"`
-	Input    []string  `json:"input" binding:"required"`
-	FileName string    `json:"file_name,omitempty"`
+	TaskID     string        `json:"tid" binding:"required"`
+	GP         GrepParam     `json:"grep_param" binding:"required"`
+	Input      []string      `json:"input" binding:"required"`
+	FileName   string        `json:"file_name,omitempty"`
+	Binary     bool          `json:"binary,omitempty"`      // мастер распознал вход как двоичный(NUL-байты или невалидный UTF-8)
+	Offsets    []int64       `json:"offsets,omitempty"`     // смещения строк Input от начала задания - передаются только при -b
+	BaseOffset int64         `json:"base_offset,omitempty"` // смещение начала задания во входе - для заданий с середины файла
+	BaseLine   int           `json:"base_line,omitempty"`   // кол-во строк входа перед началом задания
+	Paths      []string      `json:"paths,omitempty"`       // --remote — пути/glob-шаблоны файлов на диске slave-ноды вместо Input
+	Features   []string      `json:"features,omitempty"`    // возможности, без которых задание не выполнить правильно(GrepParam.RequiredFeatures)
+	Carry      *ContextCarry `json:"carry,omitempty"`       // --follow с -A/-B: вывод контекста после предыдущего задания того же файла
 }
+
+// ContextCarry - вывод контекста -A/-B на стыке заданий одного файла в --follow: задание продолжает вывод
+// предыдущего так, как если бы файл искался целиком. В результате - то же состояние на конец задания(Before там 0)
+type ContextCarry struct {
+	Before      int `json:"before,omitempty"`       // первые Before строк Input - конец предыдущего задания: они уже искались и выводятся только как контекст до совпадения
+	After       int `json:"after,omitempty"`        // сколько строк контекста после совпадения еще не выведено
+	LastPrinted int `json:"last_printed,omitempty"` // номер во входе(BaseLine + номер в задании) последней выведенной строки; 0 - строк еще не выводилось
+}
+
 type SlaveResult struct {
-	TaskID   string   `json:"tid" binding:"required"`
-	HashSumm uint64   `json:"hash" binding:"required"`
-	Output   []string `json:"output" binding:"required"`
+	TaskID   string        `json:"tid" binding:"required"`
+	HashSumm uint64        `json:"hash" binding:"required"`
+	Output   []string      `json:"output" binding:"required"`
+	Selected int           `json:"selected,omitempty"` // кол-во выбранных строк(с учетом -v) - заполняется только при -m
+	Matched  int           `json:"matched,omitempty"`  // -L: кол-во файлов задания с выбранными строками(их нет в выводе) - в хеш не входит
+	Meta     []LineMeta    `json:"meta,omitempty"`     // разметка строк Output - заполняется только при -m и --color
+	Node     string        `json:"node,omitempty"`     // имя slave-ноды(--name) - в хеш не входит
+	Errors   []string      `json:"errors,omitempty"`   // проблемы с файлами задания --remote - в хеш не входят
+	Carry    *ContextCarry `json:"carry,omitempty"`    // состояние вывода контекста в конце задания с Carry - в хеш не входит
+}
+
+// LineKind - тип строки в выводе slave-ноды
+type LineKind uint8
+
+const (
+	LineContext  = LineKind(iota) // строка контекста -A/-B/-C
+	LineSelected                  // выбранная строка(совпадение с учетом -v)
+	LineGroupSep                  // разделитель групп контекста "--"
+)
+
+// LineMeta - описание строки вывода, по которому мастер может обрезать результат задачи и подсветить строку
+type LineMeta struct {
+	Kind   LineKind `json:"k"`
+	Prefix int      `json:"p,omitempty"` // длина префикса строки(имя файла, номер строки и разделители) в байтах
+	Spans  []Span   `json:"s,omitempty"` // совпадения в тексте строки(после префикса) - только при --color
+}
+
+// Span - байтовые границы совпадения [Start, End) в тексте строки
+type Span struct {
+	Start int `json:"b"`
+	End   int `json:"e"`
 }
@@ -7,9 +7,14 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
 )
 
 var ctxPriority = map[string]int{}
@@ -25,8 +30,67 @@ func InitAppMode(osArgs []string) (*model.AppInit, error) {
 	e := flagParser.Bool("i", false, "all input lines will be lower-cased for search as well as the pattern itself")
 	f := flagParser.Bool("v", false, "search only lines that DON'T match the specified pattern")
 	g := flagParser.Bool("F", false, "specified pattern will be used strictly as a string, not regexp")
+	_ = flagParser.Bool("G", false, "pattern is a POSIX basic regexp(default)")
+	ere := flagParser.Bool("E", false, "pattern is a POSIX extended regexp")
 	h := flagParser.Bool("n", false, "enumerates output lines according to their order in input")
+	byteOffset := flagParser.Bool("b", false, "print the 0-based byte offset of each output line within its input before the line")
+	column := flagParser.Bool("column", false, "print the 1-based column(in bytes) of the first match in each selected line")
+	l := flagParser.Bool("l", false, "print only names of files containing selected lines(stops reading a file at its first match)")
+	bigL := flagParser.Bool("L", false, "print only names of files containing no selected lines")
+	m := flagParser.Int("m", -1, "stop after N selected lines in total(counted across all files in input order)")
+	r := flagParser.Bool("r", false, "search directories recursively, following only symlinks given on the command line")
+	bigR := flagParser.Bool("R", false, "search directories recursively, following all symlinks")
+	flagParser.Var(&appInit.SearchParam.Walk.Include, "include", "search only files whose name matches GLOB(may be repeated)")
+	flagParser.Var(&appInit.SearchParam.Walk.Exclude, "exclude", "skip files whose name matches GLOB(may be repeated)")
+	flagParser.Var(&appInit.SearchParam.Walk.ExcludeDir, "exclude-dir", "skip directories whose name matches GLOB(may be repeated)")
+	filesFrom := flagParser.String("files-from", "", "read the names of files to search from FILE, one per line('-' reads stdin)")
+	files0From := flagParser.String("files0-from", "", "like --files-from, but names in FILE are separated by NUL(e.g. 'find -print0')")
+	unzip := flagParser.Bool("decompress", false, "decompress gzip, bzip2 and zlib input(files and stdin) before searching")
+	maxLine := flagParser.Int("max-line-size", reader.DefaultMaxLineSize, "max length of an input line in bytes")
+	longLines := flagParser.String("long-lines", model.LongLinesError, "what to do with lines longer than --max-line-size: 'error' or 'truncate'(cut and mark the line)")
+	encoding := flagParser.String("encoding", reader.EncodingAuto, "input encoding(IANA name, e.g. 'utf-16le', 'latin1', 'windows-1251'); 'auto' recognizes UTF-8 and UTF-16 by BOM")
+	archives := flagParser.Bool("archives", false, "search every file inside tar(.tar, .tar.gz, .tar.bz2) and zip archives separately, named 'ARCHIVE:PATH'")
+	maxMember := flagParser.Int64("max-member-size", reader.DefaultMaxMemberSize, "skip files inside archives larger than N bytes")
+	maxArchive := flagParser.Int64("max-archive-size", reader.DefaultMaxArchiveSize, "don't read an archive whose files together are larger than N bytes")
+	maxMembers := flagParser.Int("max-archive-members", reader.DefaultMaxArchiveMembers, "don't read an archive with more than N files")
+	follow := flagParser.Bool("follow", false, "keep watching the files for appended lines(like 'tail -F | grep') until interrupted")
+	pollEvery := flagParser.Duration("follow-interval", time.Second, "how often files are checked for new lines with --follow")
+	remote := flagParser.Bool("remote", false, "file operands are paths or globs on each slave-node's own disk(relative to its --root), the master reads nothing")
+	noQuorum := flagParser.Bool("no-quorum", false, "don't vote: print every slave-node's result separately, prefixed with the node name")
+	noMessages := flagParser.Bool("s", false, "suppress error messages about nonexistent or unreadable files(the exit status is still 2)")
+	maxOpen := flagParser.Int("max-open", 8, "max number of input files the master keeps open at once")
+	text := flagParser.Bool("a", false, "process a binary file as if it were text(same as --binary-files=text)")
+	noBinary := flagParser.Bool("I", false, "assume binary files don't match(same as --binary-files=without-match)")
+	binaryFiles := flagParser.String("binary-files", model.BinaryMatches, "how to handle binary files: 'binary'(print only \"Binary file X matches\"), 'text' or 'without-match'")
+	z := flagParser.Bool("z", false, "input and output records are terminated by NUL instead of newline")
+	bigZ := flagParser.Bool("Z", false, "output NUL instead of ':' after file names(and after names printed by -l/-L)")
+	nameMode := fileNameAuto
+	flagParser.Var(&fileNameFlag{mode: &nameMode, set: fileNameAlways}, "H", "print the file name for each match(stdin is named '(standard input)' or --label)")
+	flagParser.Var(&fileNameFlag{mode: &nameMode, set: fileNameNever}, "h", "never print file names, even if several files are searched")
+	var groupSep groupSepFlag
+	flagParser.Var(&groupSep, "group-separator", "print SEP between groups of context lines instead of '--'")
+	flagParser.Var(&noGroupSepFlag{&groupSep}, "no-group-separator", "don't print any separator between groups of context lines")
+	heading := flagParser.Bool("heading", false, "print the file name once above its lines instead of on every line")
+	label := flagParser.String("label", "", "display stdin input as coming from file NAME")
+	color := colorFlag{when: colorAuto}
+	flagParser.Var(&color, "color", "highlight matches, file names, line numbers and separators: 'auto'(default, only if stdout is a terminal), 'always' or 'never'")
+	flagParser.Var(&color, "colour", "same as --color")
 	addr := flagParser.String("addr", "", "specify slave-node address")
+	root := flagParser.String("root", "", "allow --remote tasks to search files inside DIR on this slave-node")
+	nodeName := flagParser.String("name", "", "slave-node name shown with --no-quorum(the master uses the node address by default)")
+	flagParser.StringVar(&appInit.TLS.CertFile, "tls-cert", "", "PEM certificate: a slave-node serves TLS with it, the master presents it to slave-nodes(mTLS)")
+	flagParser.StringVar(&appInit.TLS.KeyFile, "tls-key", "", "PEM private key for --tls-cert")
+	flagParser.StringVar(&appInit.TLS.CAFile, "tls-ca", "", "PEM CA: a slave-node accepts only masters with a client certificate signed by it, the master trusts only slave-nodes signed by it")
+	flagParser.StringVar(&appInit.AuthTokens, "auth-tokens", "", "slave-node: accept only requests with a bearer token from FILE('TOKEN SCOPE[,SCOPE]' per line, scopes 'ping' and 'task'; re-read on change)")
+	authTokenFile := flagParser.String("auth-token-file", "", "master: send the bearer token from FILE to slave-nodes(default: $"+model.AuthTokenEnv+")")
+	transportKind := flagParser.String("transport", model.TransportHTTP, "protocol between the master and slave-nodes: 'http' or 'grpc'(must be the same on all of them)")
+	compress := flagParser.String("compress", model.CompressGzip, "master: compress tasks and ask for compressed results: 'gzip' or 'none'")
+	flagParser.Int64Var(&appInit.MaxUnzipped, "max-decompressed-size", transport.DefaultMaxDecompressedSize, "max size in bytes of a task(slave-node) or a result(master) after decompression")
+	flagParser.BoolVar(&appInit.Stats, "transfer-stats", false, "master: print to stderr how many bytes were sent and received, compressed and uncompressed")
+	flagParser.Int64Var(&appInit.MaxBody, "max-body-size", transport.DefaultMaxBodySize, "slave-node: reject tasks larger than N bytes as received(before decompression)")
+	flagParser.IntVar(&appInit.MaxLines, "max-task-lines", transport.DefaultMaxTaskLines, "slave-node: reject tasks with more than N input lines")
+	flagParser.IntVar(&appInit.MaxLineLen, "max-task-line-size", transport.DefaultMaxTaskLineSize, "slave-node: reject tasks with input lines longer than N bytes")
+	flagParser.StringVar(&appInit.Incompatible, "incompatible-nodes", model.IncompatibleSkip, "master: what to do with slave-nodes lacking features the search needs: 'skip' them or 'fail'")
 
 	q := flagParser.Int("quorum", -1, "set slave-nodes N for quorum")
 	flagParser.Var(&appInit.Slaves, "node", "set slave-node address")
@@ -38,9 +102,41 @@ func InitAppMode(osArgs []string) (*model.AppInit, error) {
 
 	appInit.Mode = model.AppMode(*mode)
 
+	switch *transportKind {
+	case model.TransportHTTP, model.TransportGRPC:
+	default:
+		return nil, fmt.Errorf("invalid --transport value %q: expected 'http' or 'grpc'", *transportKind)
+	}
+	appInit.Transport = *transportKind
+	switch *compress {
+	case model.CompressGzip, model.CompressNone:
+	default:
+		return nil, fmt.Errorf("invalid --compress value %q: expected 'gzip' or 'none'", *compress)
+	}
+	appInit.Compress = *compress
+	switch appInit.Incompatible {
+	case model.IncompatibleSkip, model.IncompatibleFail:
+	default:
+		return nil, fmt.Errorf("invalid --incompatible-nodes value %q: expected 'skip' or 'fail'", appInit.Incompatible)
+	}
+	switch {
+	case appInit.MaxUnzipped < 1:
+		return nil, errors.New("--max-decompressed-size must be positive")
+	case appInit.MaxBody < 1:
+		return nil, errors.New("--max-body-size must be positive")
+	case appInit.MaxLines < 1:
+		return nil, errors.New("--max-task-lines must be positive")
+	case appInit.MaxLineLen < 1:
+		return nil, errors.New("--max-task-line-size must be positive")
+	}
+
 	// проверяем режим
 	switch appInit.Mode {
 	case model.ModeMaster:
+		walk := appInit.SearchParam.Walk
+		walk.Recursive = *r || *bigR
+		walk.FollowSymlinks = *bigR
+		walk.KeepMissing = *follow
 		appInit.SearchParam = model.GrepParam{
 			CtxAfter:     *a,
 			CtxBefore:    *b,
@@ -50,26 +146,134 @@ func InitAppMode(osArgs []string) (*model.AppInit, error) {
 			InvertResult: *f,
 			ExactMatch:   *g,
 			EnumLine:     *h,
+			ByteOffset:   *byteOffset,
+			Column:       *column,
+
+			FilesWithMatch:    *l,
+			FilesWithoutMatch: *bigL && !*l,
+			Walk:              walk,
+			Color:             color.enabled(),
+			NullData:          *z,
+			NullName:          *bigZ,
+			PrintFileName:     nameMode == fileNameAlways,
+			NoFileName:        nameMode == fileNameNever,
+			Label:             *label,
+			GroupSeparator:    groupSep.sep,
+			NoGroupSeparator:  groupSep.none,
+			Heading:           *heading,
+			Decompress:        *unzip,
+			Archives:          *archives,
+			NoMessages:        *noMessages,
+			Follow:            *follow,
+			Remote:            *remote,
+		}
+		if *m >= 0 {
+			appInit.SearchParam.MaxCount = m
+		}
+		switch *binaryFiles {
+		case model.BinaryMatches, model.BinaryText, model.BinaryWithoutMatch:
+		default:
+			return nil, fmt.Errorf("invalid --binary-files value %q: expected 'binary', 'text' or 'without-match'", *binaryFiles)
+		}
+		appInit.SearchParam.BinaryFiles = *binaryFiles
+		if *maxLine < 1 {
+			return nil, errors.New("--max-line-size must be positive")
+		}
+		appInit.SearchParam.MaxLineSize = *maxLine
+		switch *longLines {
+		case model.LongLinesError, model.LongLinesTruncate:
+		default:
+			return nil, fmt.Errorf("invalid --long-lines value %q: expected 'error' or 'truncate'", *longLines)
+		}
+		appInit.SearchParam.LongLines = *longLines
+		if _, err := reader.LookupEncoding(*encoding); err != nil {
+			return nil, err
+		}
+		appInit.SearchParam.Encoding = *encoding
+		if *byteOffset && !strings.EqualFold(*encoding, reader.EncodingAuto) {
+			return nil, errors.New("-b cannot be combined with --encoding: offsets in the converted text are not offsets in the file")
+		}
+		if *maxMember < 1 {
+			return nil, errors.New("--max-member-size must be positive")
+		}
+		appInit.SearchParam.MaxMemberSize = *maxMember
+		if *maxArchive < 1 {
+			return nil, errors.New("--max-archive-size must be positive")
+		}
+		appInit.SearchParam.MaxArchiveSize = *maxArchive
+		if *maxMembers < 1 {
+			return nil, errors.New("--max-archive-members must be positive")
+		}
+		appInit.SearchParam.MaxArchiveMembers = *maxMembers
+		switch {
+		case *text:
+			appInit.SearchParam.BinaryFiles = model.BinaryText
+		case *noBinary:
+			appInit.SearchParam.BinaryFiles = model.BinaryWithoutMatch
 		}
 		appInit.Quorum = *q
+		appInit.NoQuorum = *noQuorum
+		if *maxOpen < 1 {
+			return nil, errors.New("--max-open must be positive")
+		}
+		appInit.MaxOpen = *maxOpen
+		if *pollEvery <= 0 {
+			return nil, errors.New("--follow-interval must be positive")
+		}
+		appInit.PollEvery = *pollEvery
 
-		if err := initMasterParam(&appInit, flagParser.Args()); err != nil {
+		dialect := dialectBRE
+		if *ere {
+			dialect = dialectERE
+		}
+		if err := readFileList(&appInit, *filesFrom, *files0From); err != nil {
+			return nil, err
+		}
+		if err := initMasterParam(&appInit, flagParser.Args(), dialect); err != nil {
+			return nil, err
+		}
+		if err := checkFollow(&appInit.SearchParam); err != nil {
 			return nil, err
 		}
+		if err := checkRemote(&appInit.SearchParam); err != nil {
+			return nil, err
+		}
+		if appInit.NoQuorum && !appInit.SearchParam.Remote {
+			return nil, errors.New("--no-quorum requires --remote: without it all slave-nodes grep the same input")
+		}
 		if len(appInit.Slaves) == 0 {
 			return nil, errors.New("at least one --node must be provided running in 'slave'-mode")
 		}
+		if err := checkTLS(&appInit); err != nil {
+			return nil, err
+		}
+		token, err := readAuthToken(*authTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		appInit.AuthToken = token
+		if err := checkAuth(&appInit); err != nil {
+			return nil, err
+		}
 	case model.ModeSlave:
 		if *addr == "" {
 			return nil, errors.New("empty slave-node address")
 		}
 		appInit.Address = *addr
+		appInit.Root = *root
+		appInit.NodeName = *nodeName
+		if err := checkTLS(&appInit); err != nil {
+			return nil, err
+		}
+		if err := checkAuth(&appInit); err != nil {
+			return nil, err
+		}
 	}
 
 	return &appInit, nil
 }
 
-func initMasterParam(ai *model.AppInit, noNameArgs []string) error {
+func initMasterParam(ai *model.AppInit, noNameArgs []string, dialect reDialect) error {
 	preprocessArgs()
 
 	if len(ai.Slaves) == 0 {
@@ -88,11 +292,6 @@ func initMasterParam(ai *model.AppInit, noNameArgs []string) error {
 	// Выравниваем значения контекста A и B по значению C
 	setABCvaluesByPriority(&ai.SearchParam)
 
-	// Приводим паттерн к нижнему регистру если стоят флаги 'F' и 'i'
-	if ai.SearchParam.IgnoreCase && ai.SearchParam.ExactMatch {
-		ai.SearchParam.Pattern = strings.ToLower(ai.SearchParam.Pattern)
-	}
-
 	// Разбираемся с паттерном и входом
 	switch len(noNameArgs) {
 	case 0:
@@ -101,28 +300,158 @@ func initMasterParam(ai *model.AppInit, noNameArgs []string) error {
 		ai.SearchParam.Pattern = noNameArgs[0]
 	default:
 		ai.SearchParam.Pattern = noNameArgs[0]
-		ai.SearchParam.Source = noNameArgs[1:]
+		ai.SearchParam.Source = append(slices.Clone(noNameArgs[1:]), ai.SearchParam.Source...) // операнды, затем --files-from
 	}
 
-	// ставим флаг чтобы печатать имя файла перед каждой строкой/суммой строк, если файлов несколько
-	if len(ai.SearchParam.Source) > 1 {
+	// ставим флаг чтобы печатать имя файла перед каждой строкой/суммой строк, если файлов несколько и нет -h;
+	// при --remote сколько файлов найдет slave-нода заранее неизвестно, поэтому имя печатается всегда
+	if (len(ai.SearchParam.Source) > 1 || ai.SearchParam.Remote) && !ai.SearchParam.NoFileName {
 		ai.SearchParam.PrintFileName = true
 	}
 
-	// сразу првоеряем корректность регулярки, если флаг F неактивен
+	// Приводим паттерн к нижнему регистру если стоят флаги 'F' и 'i'
+	if ai.SearchParam.IgnoreCase && ai.SearchParam.ExactMatch {
+		ai.SearchParam.Pattern = strings.ToLower(ai.SearchParam.Pattern)
+	}
+
+	// если флаг F неактивен - переводим BRE/ERE в синтаксис RE2 и сразу проверяем корректность регулярки
 	if !ai.SearchParam.ExactMatch {
-		var err error
+		pattern, err := translateRegexp(ai.SearchParam.Pattern, dialect)
+		if err != nil {
+			return fmt.Errorf("incorrect regexp provided: %q", err.Error())
+		}
 		if ai.SearchParam.IgnoreCase {
-			ai.SearchParam.Pattern = "(?i)" + ai.SearchParam.Pattern
+			pattern = "(?i)" + pattern
 		}
-		_, err = regexp.Compile(ai.SearchParam.Pattern)
-		if err != nil {
+		if _, err = regexp.Compile(pattern); err != nil {
 			return fmt.Errorf("incorrect regexp provided: %q", err.Error())
 		}
+		ai.SearchParam.Pattern = pattern
 	}
 	return nil
 }
 
+// readFileList читает список файлов --files-from/--files0-from в Source: так список любой длины
+// не упирается в ограничение ОС на размер аргументов командной строки
+func readFileList(ai *model.AppInit, filesFrom, files0From string) error {
+	name, nul := filesFrom, false
+	switch {
+	case filesFrom != "" && files0From != "":
+		return errors.New("--files-from and --files0-from cannot be used together")
+	case files0From != "":
+		name, nul = files0From, true
+	case filesFrom == "":
+		return nil
+	}
+
+	files, err := reader.ReadFileList(os.Stdin, name, nul)
+	if err != nil {
+		return err
+	}
+	ai.FilesFrom = name
+	ai.SearchParam.Source = files
+	return nil
+}
+
+// checkFollow проверяет, что с --follow не заданы несовместимые с ним параметры
+func checkFollow(gp *model.GrepParam) error {
+	switch {
+	case !gp.Follow:
+		return nil
+	case len(gp.Source) == 0:
+		return errors.New("--follow requires at least one file to watch")
+	case gp.CountFound || gp.FilesWithMatch || gp.FilesWithoutMatch:
+		return errors.New("--follow cannot be combined with -c, -l or -L")
+	case gp.Archives:
+		return errors.New("--follow cannot be combined with --archives")
+	case gp.Encoding != reader.EncodingAuto:
+		return errors.New("--follow cannot be combined with --encoding: followed files are read as is")
+	}
+	return nil
+}
+
+// checkRemote проверяет, что с --remote заданы пути и не заданы несовместимые с ним параметры
+func checkRemote(gp *model.GrepParam) error {
+	switch {
+	case !gp.Remote:
+		return nil
+	case len(gp.Source) == 0:
+		return errors.New("--remote requires at least one path or glob to search on slave-nodes")
+	case gp.Follow:
+		return errors.New("--remote cannot be combined with --follow")
+	case gp.Heading:
+		return errors.New("--remote cannot be combined with --heading")
+	case gp.Walk.Recursive:
+		return errors.New("--remote cannot be combined with -r or -R")
+	}
+	return nil
+}
+
+// checkTLS проверяет согласованность флагов TLS: сертификат и ключ задаются вместе, у slave-ноды проверка
+// клиентских сертификатов(--tls-ca) возможна только с ее собственным сертификатом, а мастер с флагами TLS
+// должен подключаться к нодам по https://
+func checkTLS(ai *model.AppInit) error {
+	tp := ai.TLS
+	if (tp.CertFile == "") != (tp.KeyFile == "") {
+		return errors.New("--tls-cert and --tls-key must be provided together")
+	}
+	switch ai.Mode {
+	case model.ModeSlave:
+		if tp.CAFile != "" && tp.CertFile == "" {
+			return errors.New("--tls-ca on a slave-node requires --tls-cert and --tls-key")
+		}
+	case model.ModeMaster:
+		if !tp.Enabled() {
+			return nil
+		}
+		for _, v := range ai.Slaves {
+			if !strings.HasPrefix(v, "https://") {
+				return fmt.Errorf("TLS flags are set, but slave-node %q is not an https:// address", v)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAuth запрещает токены без TLS: открытым текстом токен увидит любой, кто слушает сеть. Мастер с токеном
+// подключается к нодам только по https://, а slave-нода с --auth-tokens обязана сама работать по TLS - иначе
+// мастер не смог бы отправить ей токен и нода отвечала бы 401 на каждый запрос
+func checkAuth(ai *model.AppInit) error {
+	switch ai.Mode {
+	case model.ModeSlave:
+		if ai.AuthTokens != "" && ai.TLS.CertFile == "" {
+			return errors.New("--auth-tokens requires --tls-cert and --tls-key: tokens must not travel in cleartext")
+		}
+	case model.ModeMaster:
+		if ai.AuthToken == "" {
+			return nil
+		}
+		for _, v := range ai.Slaves {
+			if !strings.HasPrefix(v, "https://") {
+				return fmt.Errorf("an auth token is set(--auth-token-file or $%s), but slave-node %q is not an https:// address: the token would go in cleartext", model.AuthTokenEnv, v)
+			}
+		}
+	}
+	return nil
+}
+
+// readAuthToken читает токен мастера из файла(первая непустая строка), а без файла - из переменной окружения
+func readAuthToken(file string) (string, error) {
+	if file == "" {
+		return strings.TrimSpace(os.Getenv(model.AuthTokenEnv)), nil
+	}
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --auth-token-file: %w", err)
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if token := strings.TrimSpace(line); token != "" {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("--auth-token-file %q is empty", file)
+}
+
 func preprocessArgs() {
 	counter := 1
 	for _, arg := range os.Args {
@@ -158,3 +487,126 @@ func setABCvaluesByPriority(SP *model.GrepParam) {
 		}
 	}
 }
+
+// режимы вывода имени файла: последний из флагов -H/-h побеждает, как и в GNU grep
+const (
+	fileNameAuto = iota
+	fileNameAlways
+	fileNameNever
+)
+
+// fileNameFlag - булев флаг -H или -h, который пишет свой режим в общее значение
+type fileNameFlag struct {
+	mode *int // общий для -H и -h режим
+	set  int  // какой режим выставляет флаг
+}
+
+func (f *fileNameFlag) String() string {
+	return "false"
+}
+
+func (f *fileNameFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if on {
+		*f.mode = f.set
+	}
+	return nil
+}
+
+func (f *fileNameFlag) IsBoolFlag() bool {
+	return true
+}
+
+// groupSepFlag - значение --group-separator; вместе с --no-group-separator действует последний из флагов
+type groupSepFlag struct {
+	sep  *string
+	none bool
+}
+
+func (g *groupSepFlag) String() string {
+	if g.sep == nil {
+		return model.DefaultGroupSeparator
+	}
+	return *g.sep
+}
+
+func (g *groupSepFlag) Set(value string) error {
+	g.sep, g.none = &value, false
+	return nil
+}
+
+// noGroupSepFlag - булев флаг --no-group-separator, который сбрасывает общий groupSepFlag
+type noGroupSepFlag struct {
+	g *groupSepFlag
+}
+
+func (n *noGroupSepFlag) String() string {
+	return "false"
+}
+
+func (n *noGroupSepFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if on {
+		n.g.sep, n.g.none = nil, true
+	}
+	return nil
+}
+
+func (n *noGroupSepFlag) IsBoolFlag() bool {
+	return true
+}
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorFlag - значение --color[=WHEN]; без значения флаг означает 'auto', как и в GNU grep
+type colorFlag struct {
+	when string
+}
+
+func (c *colorFlag) String() string {
+	return c.when
+}
+
+func (c *colorFlag) Set(value string) error {
+	switch value {
+	case "true", "auto", "tty", "if-tty":
+		c.when = colorAuto
+	case "always", "yes", "force":
+		c.when = colorAlways
+	case "never", "no", "none", "false":
+		c.when = colorNever
+	default:
+		return fmt.Errorf("invalid --color value %q: expected 'auto', 'always' or 'never'", value)
+	}
+	return nil
+}
+
+func (c *colorFlag) IsBoolFlag() bool {
+	return true
+}
+
+// enabled решает, нужна ли подсветка: при 'auto' - только если stdout это терминал
+func (c *colorFlag) enabled() bool {
+	switch c.when {
+	case colorAlways:
+		return true
+	case colorAuto:
+		if os.Getenv("TERM") == "dumb" {
+			return false
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitAppModeAuthRequiresTLS(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "Positive - master with a token and https:// nodes",
+			args:  []string{"-mode=master", "-node=https://n1:8080", "-node=https://n2:8080", "p"},
+			token: "secret",
+		},
+		{
+			name: "Positive - master without a token and http:// nodes",
+			args: []string{"-mode=master", "-node=n1:8080", "p"},
+		},
+		{
+			name:    "Negative - master with a token and an http:// node",
+			args:    []string{"-mode=master", "-node=https://n1:8080", "-node=n2:8080", "p"},
+			token:   "secret",
+			wantErr: `slave-node "n2:8080" is not an https:// address`,
+		},
+		{
+			name: "Positive - slave with tokens and TLS",
+			args: []string{"-mode=slave", "-addr=8080", "--auth-tokens=tokens", "--tls-cert=cert.pem", "--tls-key=key.pem"},
+		},
+		{
+			name:    "Negative - slave with tokens without TLS",
+			args:    []string{"-mode=slave", "-addr=8080", "--auth-tokens=tokens"},
+			wantErr: "--auth-tokens requires --tls-cert",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(model.AuthTokenEnv, tt.token)
+			_, err := parser.InitAppMode(tt.args)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestInitAppModeByteOffsetEncoding(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "Positive - -b with auto encoding", args: []string{"-mode=master", "-node=n1:8080", "-b", "--encoding=AUTO", "p"}},
+		{name: "Positive - --encoding without -b", args: []string{"-mode=master", "-node=n1:8080", "--encoding=utf-16le", "p"}},
+		{name: "Negative - -b with --encoding", args: []string{"-mode=master", "-node=n1:8080", "-b", "--encoding=latin1", "p"}, wantErr: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(model.AuthTokenEnv, "")
+			_, err := parser.InitAppMode(tt.args)
+			require.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// reDialect - синтаксис регулярных выражений, в котором пользователь передал паттерн
+type reDialect int
+
+const (
+	dialectBRE = reDialect(iota) // -G — POSIX basic regexp с расширениями GNU(по умолчанию)
+	dialectERE                   // -E — POSIX extended regexp с расширениями GNU
+)
+
+// классы символов POSIX, которые RE2 понимает внутри [...] без изменений
+var posixClasses = map[string]struct{}{
+	"alnum": {}, "alpha": {}, "blank": {}, "cntrl": {}, "digit": {}, "graph": {},
+	"lower": {}, "print": {}, "punct": {}, "space": {}, "upper": {}, "xdigit": {},
+}
+
+// translateRegexp переводит паттерн в синтаксисе BRE/ERE(как его понимает GNU grep) в синтаксис RE2.
+// Конструкции, которые RE2 выразить не может(обратные ссылки, \< и \>), возвращают ошибку.
+func translateRegexp(pattern string, d reDialect) (string, error) {
+	t := reTranslator{
+		src:       pattern,
+		dialect:   d,
+		atomStart: -1,
+		exprStart: true,
+	}
+	if err := t.translate(); err != nil {
+		return "", err
+	}
+	return t.out.String(), nil
+}
+
+type reTranslator struct {
+	src     string
+	pos     int
+	dialect reDialect
+	out     strings.Builder
+
+	atomStart  int   // начало последнего атома в out - к нему применяется квантификатор; -1 если атома нет
+	quantified bool  // к последнему атому уже применен квантификатор
+	exprStart  bool  // находимся в начале выражения: в начале паттерна, после "(" или "|"
+	groups     []int // начала открытых групп в out
+}
+
+func (t *reTranslator) translate() error {
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c == '\\':
+			if err := t.escape(); err != nil {
+				return err
+			}
+			continue
+		case c == '[':
+			if err := t.bracket(); err != nil {
+				return err
+			}
+			continue
+		case c == '.':
+			t.atom(".")
+		case c == '*':
+			t.quantifier("*", "\\*")
+		case c == '^':
+			// в BRE '^' - якорь только в начале выражения, в ERE - везде
+			if t.dialect == dialectERE || t.exprStart {
+				t.anchor("^")
+			} else {
+				t.atom("\\^")
+			}
+		case c == '$':
+			// в BRE '$' - якорь только в конце выражения, в ERE - везде
+			if t.dialect == dialectERE || t.atExprEnd(t.pos+1) {
+				t.anchor("$")
+			} else {
+				t.atom("\\$")
+			}
+		case t.dialect == dialectERE && (c == '+' || c == '?'):
+			t.quantifier(string(c), "\\"+string(c))
+		case t.dialect == dialectERE && c == '{':
+			if err := t.interval(t.pos+1, "}"); err != nil {
+				return err
+			}
+			continue
+		case t.dialect == dialectERE && c == '(':
+			t.openGroup()
+		case t.dialect == dialectERE && c == ')':
+			if len(t.groups) == 0 { // как и GNU grep, непарная ')' в ERE - обычный символ
+				t.atom("\\)")
+			} else {
+				t.closeGroup()
+			}
+		case t.dialect == dialectERE && c == '|':
+			t.alternation()
+		default:
+			r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+			t.atom(regexp.QuoteMeta(string(r)))
+			t.pos += size
+			continue
+		}
+		t.pos++
+	}
+
+	if len(t.groups) != 0 {
+		return errors.New(`unmatched ( or \(`)
+	}
+	return nil
+}
+
+func (t *reTranslator) escape() error {
+	if t.pos+1 >= len(t.src) {
+		return errors.New("trailing backslash")
+	}
+	c := t.src[t.pos+1]
+	t.pos += 2
+
+	switch {
+	case c >= '1' && c <= '9':
+		return fmt.Errorf(`back-references(\%c) are not supported by RE2`, c)
+	case c == '<' || c == '>':
+		return fmt.Errorf(`word-boundary anchor \%c is not supported by RE2, use \b instead`, c)
+	case c == 'w' || c == 'W' || c == 's' || c == 'S':
+		t.atom("\\" + string(c))
+	case c == 'b' || c == 'B':
+		t.anchor("\\" + string(c))
+	case c == '`':
+		t.anchor("\\A")
+	case c == '\'':
+		t.anchor("\\z")
+	case t.dialect == dialectBRE && c == '(':
+		t.openGroup()
+	case t.dialect == dialectBRE && c == ')':
+		if len(t.groups) == 0 {
+			return errors.New(`unmatched ) or \)`)
+		}
+		t.closeGroup()
+	case t.dialect == dialectBRE && c == '|':
+		t.alternation()
+	case t.dialect == dialectBRE && (c == '+' || c == '?'):
+		t.quantifier(string(c), "\\"+string(c))
+	case t.dialect == dialectBRE && c == '{':
+		return t.interval(t.pos, "\\}")
+	default:
+		// экранированный обычный символ - это сам символ
+		r, size := utf8.DecodeRuneInString(t.src[t.pos-1:])
+		t.atom(regexp.QuoteMeta(string(r)))
+		t.pos += size - 1
+	}
+	return nil
+}
+
+// interval разбирает {n}, {n,}, {,m} и {n,m}; from - позиция после открывающей скобки
+func (t *reTranslator) interval(from int, closing string) error {
+	end := strings.Index(t.src[from:], closing)
+	body := ""
+	if end >= 0 {
+		body = t.src[from : from+end]
+	}
+
+	minS, maxS, hasComma := strings.Cut(body, ",")
+	lo, errLo := strconv.Atoi(minS)
+	hi, errHi := strconv.Atoi(maxS)
+	valid := end >= 0 && (minS == "" || errLo == nil) && (maxS == "" || errHi == nil) && (minS != "" || hasComma)
+
+	if !valid {
+		if t.dialect == dialectERE { // как и GNU grep, некорректный интервал в ERE - обычный текст
+			t.atom("\\{")
+			t.pos = from
+			return nil
+		}
+		if end < 0 {
+			return errors.New(`unmatched \{`)
+		}
+		return errors.New(`invalid content of \{\}`)
+	}
+
+	var q string
+	switch {
+	case !hasComma:
+		q = "{" + strconv.Itoa(lo) + "}"
+	case maxS == "":
+		q = "{" + strconv.Itoa(lo) + ",}"
+	default:
+		if hi < lo {
+			return errors.New(`invalid content of \{\}`)
+		}
+		q = "{" + strconv.Itoa(lo) + "," + strconv.Itoa(hi) + "}"
+	}
+
+	t.pos = from + end + len(closing)
+	if t.dialect == dialectBRE && t.atomStart < 0 { // в начале BRE интервал - обычный текст
+		t.atom(regexp.QuoteMeta(t.src[from-2 : t.pos]))
+		return nil
+	}
+	t.quantifier(q, "")
+	return nil
+}
+
+// bracket переносит выражение [...] - внутри него обратный слеш в POSIX не экранирует
+func (t *reTranslator) bracket() error {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	i := t.pos + 1
+	if i < len(t.src) && t.src[i] == '^' {
+		sb.WriteByte('^')
+		i++
+	}
+
+	for first := true; ; first = false {
+		if i >= len(t.src) {
+			return errors.New("unmatched [, [^, [:, [., or [=")
+		}
+		c := t.src[i]
+		if c == ']' && !first {
+			i++
+			break
+		}
+
+		if c == '[' && i+1 < len(t.src) && strings.IndexByte(":=.", t.src[i+1]) >= 0 {
+			delim := t.src[i+1]
+			end := strings.Index(t.src[i+2:], string(delim)+"]")
+			if end < 0 {
+				return errors.New("unmatched [, [^, [:, [., or [=")
+			}
+			name := t.src[i+2 : i+2+end]
+			i += 2 + end + 2
+
+			switch delim {
+			case ':':
+				if _, ok := posixClasses[name]; !ok {
+					return fmt.Errorf("invalid character class name %q", name)
+				}
+				sb.WriteString("[:" + name + ":]")
+			default: // [=a=] и [.a.] поддерживаются только для одиночного символа
+				if utf8.RuneCountInString(name) != 1 {
+					return fmt.Errorf("collating element [%c%s%c] is not supported by RE2", delim, name, delim)
+				}
+				sb.WriteString(quoteClassRune(name))
+			}
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(t.src[i:])
+		sb.WriteString(quoteClassRune(string(r)))
+		i += size
+	}
+	sb.WriteByte(']')
+
+	t.atom(sb.String())
+	t.pos = i
+	return nil
+}
+
+func quoteClassRune(r string) string {
+	switch r {
+	case "\\", "[", "]", "^":
+		return "\\" + r
+	}
+	return r
+}
+
+func (t *reTranslator) atom(s string) {
+	t.atomStart = t.out.Len()
+	t.quantified = false
+	t.exprStart = false
+	t.out.WriteString(s)
+}
+
+func (t *reTranslator) anchor(s string) {
+	t.atomStart = -1
+	t.quantified = false
+	t.out.WriteString(s)
+}
+
+// quantifier применяет q к последнему атому; literal - во что превращается квантификатор без атома в BRE
+func (t *reTranslator) quantifier(q, literal string) {
+	if t.atomStart < 0 {
+		// в начале выражения BRE квантификатор - обычный символ, а ERE(как GNU grep) его игнорирует
+		if t.dialect == dialectBRE && literal != "" {
+			t.atom(literal)
+		}
+		return
+	}
+	if t.quantified {
+		// RE2 не допускает "a**" или "a+?"(это нежадный квантификатор) - оборачиваем атом в группу
+		s := t.out.String()
+		t.out.Reset()
+		t.out.WriteString(s[:t.atomStart] + "(?:" + s[t.atomStart:] + ")")
+	}
+	t.out.WriteString(q)
+	t.quantified = true
+	t.exprStart = false
+}
+
+func (t *reTranslator) openGroup() {
+	t.groups = append(t.groups, t.out.Len())
+	t.out.WriteByte('(')
+	t.atomStart = -1
+	t.quantified = false
+	t.exprStart = true
+}
+
+func (t *reTranslator) closeGroup() {
+	start := t.groups[len(t.groups)-1]
+	t.groups = t.groups[:len(t.groups)-1]
+	t.out.WriteByte(')')
+	t.atomStart = start
+	t.quantified = false
+	t.exprStart = false
+}
+
+func (t *reTranslator) alternation() {
+	t.out.WriteByte('|')
+	t.atomStart = -1
+	t.quantified = false
+	t.exprStart = true
+}
+
+// atExprEnd проверяет, заканчивается ли BRE-выражение в позиции i: конец паттерна, "\)" или "\|"
+func (t *reTranslator) atExprEnd(i int) bool {
+	rest := t.src[i:]
+	return rest == "" || strings.HasPrefix(rest, "\\)") || strings.HasPrefix(rest, "\\|")
+}
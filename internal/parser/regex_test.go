@@ -0,0 +1,151 @@
+package parser_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// строки, на которых сравнивается поведение с GNU grep
+var regexpInput = []string{
+	"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b",
+	"a$b", "ab$", `a\b`, "aa", "{1}", "a.c", "abab", "a]b", "a-b",
+}
+
+// ожидаемые результаты получены запуском GNU grep 3.8 на regexpInput
+var regexpCases = []struct {
+	flag    string
+	pattern string
+	want    []string
+}{
+	{flag: "-G", pattern: "abc", want: []string{"abc", "*abc"}},
+	{flag: "-G", pattern: "*abc", want: []string{"*abc"}},
+	{flag: "-G", pattern: "^*abc", want: []string{"*abc"}},
+	{flag: "-G", pattern: "a+b", want: []string{"a+b"}},
+	{flag: "-G", pattern: "a\\+b", want: []string{"abc", "*abc", "aab", "(ab)", "ab$", "abab"}},
+	{flag: "-G", pattern: "a\\?b", want: []string{"abc", "*abc", "a+b", "aab", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "abab", "a]b", "a-b"}},
+	{flag: "-G", pattern: "a\\{2\\}", want: []string{"aab", "aa"}},
+	{flag: "-G", pattern: "a{2}", want: []string{"a{2}"}},
+	{flag: "-G", pattern: "a\\|x", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-G", pattern: "\\(ab\\)\\{2\\}", want: []string{"abab"}},
+	{flag: "-G", pattern: "\\(*a\\)", want: []string{"*abc"}},
+	{flag: "-G", pattern: "a^b", want: []string{"a^b"}},
+	{flag: "-G", pattern: "a$b", want: []string{"a$b"}},
+	{flag: "-G", pattern: "ab$", want: []string{"aab", "abab"}},
+	{flag: "-G", pattern: "a**", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "{1}", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-G", pattern: "[]]", want: []string{"a]b"}},
+	{flag: "-G", pattern: "[a\\]b", want: []string{"abc", "*abc", "aab", "(ab)", "ab$", "a\\b", "abab"}},
+	{flag: "-G", pattern: "a\\{,1\\}b", want: []string{"abc", "*abc", "a+b", "aab", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "abab", "a]b", "a-b"}},
+	{flag: "-G", pattern: "[[:digit:]x]", want: []string{"a{2}", "x", "{1}"}},
+	{flag: "-G", pattern: "\\+a", want: []string{}},
+	{flag: "-G", pattern: "\\{1\\}a", want: []string{}},
+	{flag: "-G", pattern: "[[.a.]]b", want: []string{"abc", "*abc", "aab", "(ab)", "ab$", "abab"}},
+	{flag: "-G", pattern: "a\\.c", want: []string{"a.c"}},
+	{flag: "-G", pattern: "\\w\\]", want: []string{"a]b"}},
+	{flag: "-G", pattern: "\\`a", want: []string{"abc", "a+b", "aab", "a{2}", "a|b", "a^b", "a$b", "ab$", "a\\b", "aa", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-G", pattern: "b$\\|^x", want: []string{"a+b", "aab", "a|b", "x", "a^b", "a$b", "a\\b", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "*abc", want: []string{"abc", "*abc"}},
+	{flag: "-E", pattern: "a+b", want: []string{"abc", "*abc", "aab", "(ab)", "ab$", "abab"}},
+	{flag: "-E", pattern: "a{2}", want: []string{"aab", "aa"}},
+	{flag: "-E", pattern: "a{", want: []string{"a{2}"}},
+	{flag: "-E", pattern: "a{x}", want: []string{}},
+	{flag: "-E", pattern: "{1}", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "{1}", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "a|x", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "(ab){2}", want: []string{"abab"}},
+	{flag: "-E", pattern: "a^b", want: []string{}},
+	{flag: "-E", pattern: "a\\$b", want: []string{"a$b"}},
+	{flag: "-E", pattern: "a{,1}b", want: []string{"abc", "*abc", "a+b", "aab", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "a**", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "{1}", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "()a", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "(|a)b", want: []string{"abc", "*abc", "a+b", "aab", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "a+?", want: []string{"abc", "*abc", "a+b", "aab", "a{2}", "a|b", "x", "(ab)", "a^b", "a$b", "ab$", "a\\b", "aa", "{1}", "a.c", "abab", "a]b", "a-b"}},
+	{flag: "-E", pattern: "a)", want: []string{}},
+	{flag: "-E", pattern: "[^a-z]", want: []string{"*abc", "a+b", "a{2}", "a|b", "(ab)", "a^b", "a$b", "ab$", "a\\b", "{1}", "a.c", "a]b", "a-b"}},
+	{flag: "-E", pattern: "\\(ab\\)", want: []string{"(ab)"}},
+	{flag: "-E", pattern: "a.c|^x$", want: []string{"abc", "*abc", "x", "a.c"}},
+}
+
+func TestRegexpDialects(t *testing.T) {
+	for _, tt := range regexpCases {
+		t.Run(tt.flag+" "+tt.pattern, func(t *testing.T) {
+			require.Equal(t, tt.want, grepLines(t, tt.flag, tt.pattern))
+		})
+	}
+}
+
+// TestRegexpDialectsAgainstGNUGrep сверяет те же случаи с установленным в системе GNU grep
+func TestRegexpDialectsAgainstGNUGrep(t *testing.T) {
+	version, err := exec.Command("grep", "--version").Output()
+	if err != nil || !bytes.Contains(version, []byte("GNU grep")) {
+		t.Skip("GNU grep is not available")
+	}
+
+	input := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(input, []byte(strings.Join(regexpInput, "\n")+"\n"), 0o644))
+
+	for _, tt := range regexpCases {
+		t.Run(tt.flag+" "+tt.pattern, func(t *testing.T) {
+			out, _ := exec.Command("grep", tt.flag, "--", tt.pattern, input).Output() // код 1 - нет совпадений
+			want := []string{}
+			if len(out) != 0 {
+				want = strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+			}
+			require.Equal(t, want, grepLines(t, tt.flag, tt.pattern))
+		})
+	}
+}
+
+func TestRegexpUnsupported(t *testing.T) {
+	cases := []struct {
+		flag    string
+		pattern string
+		wantErr string
+	}{
+		{flag: "-G", pattern: `\(a\)\1`, wantErr: "back-references"},
+		{flag: "-E", pattern: `(a)\1`, wantErr: "back-references"},
+		{flag: "-G", pattern: `\<abc`, wantErr: "word-boundary anchor"},
+		{flag: "-E", pattern: `abc\>`, wantErr: "word-boundary anchor"},
+		{flag: "-G", pattern: `a\`, wantErr: "trailing backslash"},
+		{flag: "-G", pattern: `\(a`, wantErr: "unmatched ("},
+		{flag: "-E", pattern: `(a`, wantErr: "unmatched ("},
+		{flag: "-G", pattern: `a\)`, wantErr: "unmatched )"},
+		{flag: "-G", pattern: `a\{1`, wantErr: `unmatched \\{`},
+		{flag: "-G", pattern: `a\{x\}`, wantErr: "invalid content"},
+		{flag: "-E", pattern: `a{2,1}`, wantErr: "invalid content"},
+		{flag: "-E", pattern: `[a`, wantErr: "unmatched ["},
+		{flag: "-E", pattern: `[[:foo:]]`, wantErr: "invalid character class"},
+		{flag: "-G", pattern: `[[.ch.]]`, wantErr: "collating element"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.flag+" "+tt.pattern, func(t *testing.T) {
+			_, err := parser.InitAppMode([]string{"-mode=master", "-node=localhost:8080", tt.flag, tt.pattern})
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// grepLines прогоняет regexpInput через паттерн, переведенный парсером в RE2
+func grepLines(t *testing.T, flag, pattern string) []string {
+	t.Helper()
+	ai, err := parser.InitAppMode([]string{"-mode=master", "-node=localhost:8080", flag, pattern})
+	require.NoError(t, err)
+
+	re, err := regexp.Compile(ai.SearchParam.Pattern)
+	require.NoError(t, err)
+
+	res := []string{}
+	for _, line := range regexpInput {
+		if re.MatchString(line) {
+			res = append(res, line)
+		}
+	}
+	return res
+}
@@ -0,0 +1,275 @@
+// Package printer writes quorum-confirmed results to the master's output, optionally highlighting them GNU-style
+package printer
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+)
+
+// SGR-последовательности по умолчанию, как в GREP_COLORS у GNU grep: ms=01;31:mc=01;31:fn=35:ln=32:bn=32:se=36
+const (
+	sgrMatch     = "01;31"
+	sgrFileName  = "35"
+	sgrLineNum   = "32"
+	sgrSeparator = "36"
+)
+
+type Printer struct {
+	w       *bufio.Writer
+	color   bool
+	records bool   // уже выведены строки какого-либо файла - перед строками следующего нужен разделитель
+	last    string // файл последних выведенных строк
+}
+
+func New(w io.Writer, color bool) *Printer {
+	return &Printer{
+		w:     bufio.NewWriter(w),
+		color: color,
+	}
+}
+
+// Print выводит строки результата одного задания; meta может быть пустой - тогда строки печатаются как есть.
+// Задания печатаются по порядку, и разделители между группами контекста разных файлов, а также
+// заголовки --heading расставляет сам Printer
+func (p *Printer) Print(task *model.TaskDTO, output []string, meta []model.LineMeta) error {
+	return p.PrintNode("", task, output, meta)
+}
+
+// PrintNode работает как Print, но при непустом node начинает каждую строку с имени slave-ноды(--no-quorum)
+func (p *Printer) PrintNode(node string, task *model.TaskDTO, output []string, meta []model.LineMeta) error {
+	if len(output) == 0 {
+		return nil
+	}
+	gp := &task.GP
+	if len(meta) != len(output) {
+		meta = nil
+	}
+
+	sepDone := false // разделитель уже выведен - такой же в начале вывода задания пропускается
+	if isRecords(task) {
+		// --follow: задание продолжает вывод того же файла - разделитель групп, если он нужен, прислала slave-нода
+		continues := task.Carry != nil && task.Carry.LastPrinted > 0 && p.records && p.last == task.FileName
+		if p.records && !continues {
+			if err := p.fileSeparator(task); err != nil {
+				return err
+			}
+			sepDone = true
+		}
+		p.records = true
+		p.last = task.FileName
+
+		if gp.Heading && gp.PrintFileName && !continues {
+			name := gp.InputName(task.FileName)
+			if p.color {
+				name = sgr(sgrFileName, name)
+			}
+			if err := p.writeLine(name, '\n'); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, line := range output {
+		var lm *model.LineMeta
+		if meta != nil {
+			lm = &meta[i]
+		}
+		if lm != nil && lm.Kind == model.LineGroupSep {
+			sep, ok := gp.GroupSep()
+			if !ok || (i == 0 && sepDone) {
+				continue
+			}
+			line = sep
+		}
+		if p.color {
+			line = p.colorize(task, line, lm)
+		}
+		if node != "" && (lm == nil || lm.Kind != model.LineGroupSep) {
+			line = p.nodeTag(node) + line
+		}
+		if err := p.writeLine(line, terminator(task, lm)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileSeparator разделяет строки двух файлов: при --heading - пустой строкой, как в ripgrep,
+// а при выводе контекста - разделителем групп, как в GNU grep
+func (p *Printer) fileSeparator(task *model.TaskDTO) error {
+	gp := &task.GP
+	if gp.Heading {
+		return p.writeLine("", '\n')
+	}
+	if gp.CtxAfter == 0 && gp.CtxBefore == 0 {
+		return nil
+	}
+	sep, ok := gp.GroupSep()
+	if !ok {
+		return nil
+	}
+	if p.color {
+		sep = sgr(sgrSeparator, sep)
+	}
+	return p.writeLine(sep, '\n')
+}
+
+func (p *Printer) nodeTag(node string) string {
+	if p.color {
+		return sgr(sgrFileName, node) + sgr(sgrSeparator, ":")
+	}
+	return node + ":"
+}
+
+func (p *Printer) writeLine(line string, term byte) error {
+	if _, err := p.w.WriteString(line); err != nil {
+		return err
+	}
+	return p.w.WriteByte(term)
+}
+
+// isRecords сообщает, состоит ли результат задания из строк входа - в отличие от -c, -l/-L
+// и сообщения о совпадении в двоичном файле
+func isRecords(task *model.TaskDTO) bool {
+	gp := &task.GP
+	binary := task.Binary && gp.BinaryFiles != model.BinaryText
+	return !gp.CountFound && !gp.FilesWithMatch && !gp.FilesWithoutMatch && !binary
+}
+
+// terminator - чем завершается строка вывода. Как и в GNU grep, NUL-байтом при -z завершаются только
+// записи входа, а при -Z - имена файлов в -l/-L; счетчики, разделители групп и сообщения завершаются '\n'
+func terminator(task *model.TaskDTO, lm *model.LineMeta) byte {
+	gp := &task.GP
+	switch {
+	case gp.FilesWithMatch || gp.FilesWithoutMatch:
+		if gp.NullName {
+			return 0
+		}
+		return '\n'
+	case gp.CountFound, task.Binary && gp.BinaryFiles != model.BinaryText:
+		return '\n'
+	case gp.NullData && (lm == nil || lm.Kind != model.LineGroupSep):
+		return 0
+	default:
+		return '\n'
+	}
+}
+
+// Flush дописывает буферизованный вывод
+func (p *Printer) Flush() error {
+	return p.w.Flush()
+}
+
+func (p *Printer) colorize(task *model.TaskDTO, line string, lm *model.LineMeta) string {
+	gp := &task.GP
+	fileName := gp.InputName(task.FileName)
+
+	switch {
+	case gp.FilesWithMatch || gp.FilesWithoutMatch: // -l/-L: строка - это имя файла
+		return sgr(sgrFileName, line)
+	case gp.CountFound: // -c: [имя файла:]число
+		if len(task.Paths) != 0 { // --remote: имя файла знает только slave-нода
+			fileName = line[:max(strings.LastIndexAny(line, ":\x00"), 0)]
+		}
+		if gp.PrintFileName && strings.HasPrefix(line, fileName) && len(line) > len(fileName) {
+			return sgr(sgrFileName, fileName) + sgrSep(line[len(fileName):len(fileName)+1]) + line[len(fileName)+1:]
+		}
+		return line
+	case lm == nil:
+		return line
+	case lm.Kind == model.LineGroupSep:
+		return sgr(sgrSeparator, line)
+	}
+
+	if lm.Prefix < 0 || lm.Prefix > len(line) {
+		return line
+	}
+
+	var sb strings.Builder
+	prefix, text := line[:lm.Prefix], line[lm.Prefix:]
+	if len(task.Paths) != 0 {
+		fileName = remoteFileName(gp, prefix, lm)
+	}
+
+	// префикс: [имя файла разделитель](число разделитель)*
+	if gp.PrintFileName {
+		if strings.HasPrefix(prefix, fileName) && len(prefix) > len(fileName) {
+			sb.WriteString(sgr(sgrFileName, fileName))
+			sb.WriteString(sgrSep(prefix[len(fileName) : len(fileName)+1]))
+			prefix = prefix[len(fileName)+1:]
+		}
+	}
+	for prefix != "" {
+		n := 0
+		for n < len(prefix) && prefix[n] >= '0' && prefix[n] <= '9' {
+			n++
+		}
+		if n == 0 || n == len(prefix) { // неожиданный формат префикса - выводим остаток как есть
+			sb.WriteString(prefix)
+			break
+		}
+		sb.WriteString(sgr(sgrLineNum, prefix[:n]))
+		sb.WriteString(sgrSep(prefix[n : n+1]))
+		prefix = prefix[n+1:]
+	}
+
+	// текст строки с подсветкой совпадений
+	last := 0
+	for _, sp := range lm.Spans {
+		if sp.Start < last || sp.End > len(text) || sp.Start >= sp.End {
+			continue
+		}
+		sb.WriteString(text[last:sp.Start])
+		sb.WriteString(sgr(sgrMatch, text[sp.Start:sp.End]))
+		last = sp.End
+	}
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+// remoteFileName выделяет имя файла из префикса строки задания --remote: файлов в таком задании
+// несколько, и их имена знает только slave-нода. Справа от имени стоят числовые поля -n, -b и --column
+func remoteFileName(gp *model.GrepParam, prefix string, lm *model.LineMeta) string {
+	fields := 0
+	if gp.EnumLine {
+		fields++
+	}
+	if gp.ByteOffset {
+		fields++
+	}
+	if gp.Column && lm.Kind == model.LineSelected {
+		fields++
+	}
+
+	end := len(prefix)
+	for range fields { // каждое поле - "число:"
+		if end == 0 || prefix[end-1] != ':' {
+			return ""
+		}
+		end--
+		for end > 0 && prefix[end-1] >= '0' && prefix[end-1] <= '9' {
+			end--
+		}
+	}
+	if end == 0 {
+		return ""
+	}
+	return prefix[:end-1] // без разделителя после имени
+}
+
+// sgr оборачивает s в SGR-последовательность так же, как GNU grep(с очисткой до конца строки)
+func sgr(code, s string) string {
+	return "\x1b[" + code + "m\x1b[K" + s + "\x1b[m\x1b[K"
+}
+
+// sgrSep подсвечивает разделитель; NUL-байт после имени файла(-Z) выводится как есть
+func sgrSep(sep string) string {
+	if sep == "\x00" {
+		return sep
+	}
+	return sgr(sgrSeparator, sep)
+}
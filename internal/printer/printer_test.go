@@ -0,0 +1,256 @@
+package printer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrint(t *testing.T) {
+	cases := []struct {
+		name    string
+		color   bool
+		task    *model.TaskDTO
+		output  []string
+		meta    []model.LineMeta
+		wantOut string
+	}{
+		{
+			name:    "Positive - no color",
+			color:   false,
+			task:    &model.TaskDTO{GP: model.GrepParam{EnumLine: true, Color: true}},
+			output:  []string{"1:abc"},
+			meta:    []model.LineMeta{{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 0, End: 1}}}},
+			wantOut: "1:abc\n",
+		},
+		{
+			name:  "Positive - color file name, line number and matches",
+			color: true,
+			task:  &model.TaskDTO{FileName: "f1", GP: model.GrepParam{EnumLine: true, PrintFileName: true, Color: true}},
+			output: []string{
+				"f1:1:xabcab",
+				"f1:2:ctx",
+				"--",
+			},
+			meta: []model.LineMeta{
+				{Kind: model.LineSelected, Prefix: 5, Spans: []model.Span{{Start: 1, End: 4}, {Start: 4, End: 6}}},
+				{Kind: model.LineContext, Prefix: 5},
+				{Kind: model.LineGroupSep},
+			},
+			wantOut: "\x1b[35m\x1b[Kf1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K\x1b[32m\x1b[K1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K" +
+				"x\x1b[01;31m\x1b[Kabc\x1b[m\x1b[K\x1b[01;31m\x1b[Kab\x1b[m\x1b[K\n" +
+				"\x1b[35m\x1b[Kf1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K\x1b[32m\x1b[K2\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[Kctx\n" +
+				"\x1b[36m\x1b[K--\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "Positive - color count with file name",
+			color:   true,
+			task:    &model.TaskDTO{FileName: "f1", GP: model.GrepParam{CountFound: true, PrintFileName: true}},
+			output:  []string{"f1:3"},
+			wantOut: "\x1b[35m\x1b[Kf1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K3\n",
+		},
+		{
+			name:    "Positive - color files with matches",
+			color:   true,
+			task:    &model.TaskDTO{FileName: "f1", GP: model.GrepParam{FilesWithMatch: true}},
+			output:  []string{"f1"},
+			wantOut: "\x1b[35m\x1b[Kf1\x1b[m\x1b[K\n",
+		},
+		{
+			name:  "Positive - null-terminated records, newline after group separator",
+			color: false,
+			task:  &model.TaskDTO{GP: model.GrepParam{NullData: true}},
+			output: []string{
+				"a1",
+				"--",
+				"a2",
+			},
+			meta: []model.LineMeta{
+				{Kind: model.LineSelected},
+				{Kind: model.LineGroupSep},
+				{Kind: model.LineSelected},
+			},
+			wantOut: "a1\x00--\na2\x00",
+		},
+		{
+			name:    "Positive - null-terminated records, newline after count",
+			color:   false,
+			task:    &model.TaskDTO{FileName: "f1", GP: model.GrepParam{NullData: true, NullName: true, CountFound: true, PrintFileName: true}},
+			output:  []string{"f1\x003"},
+			wantOut: "f1\x003\n",
+		},
+		{
+			name:    "Positive - color files with matches, null after name",
+			color:   true,
+			task:    &model.TaskDTO{FileName: "f1", GP: model.GrepParam{FilesWithMatch: true, NullName: true}},
+			output:  []string{"f1"},
+			wantOut: "\x1b[35m\x1b[Kf1\x1b[m\x1b[K\x00",
+		},
+		{
+			name:   "Positive - color --remote file name taken from the line",
+			color:  true,
+			task:   &model.TaskDTO{FileName: "logs/*.log", Paths: []string{"logs/*.log"}, GP: model.GrepParam{EnumLine: true, PrintFileName: true, Remote: true}},
+			output: []string{"logs/a:1.log:12:abc"},
+			meta:   []model.LineMeta{{Kind: model.LineSelected, Prefix: 16}},
+			wantOut: "\x1b[35m\x1b[Klogs/a:1.log\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K" +
+				"\x1b[32m\x1b[K12\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[Kabc\n",
+		},
+		{
+			name:    "Positive - color --remote count",
+			color:   true,
+			task:    &model.TaskDTO{FileName: "logs/*.log", Paths: []string{"logs/*.log"}, GP: model.GrepParam{CountFound: true, PrintFileName: true, Remote: true}},
+			output:  []string{"logs/a.log:3"},
+			meta:    []model.LineMeta{{Kind: model.LineSelected}},
+			wantOut: "\x1b[35m\x1b[Klogs/a.log\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K3\n",
+		},
+		{
+			name:    "Negative - markup doesn't fit the output, line printed as is",
+			color:   true,
+			task:    &model.TaskDTO{GP: model.GrepParam{Color: true}},
+			output:  []string{"abc"},
+			meta:    []model.LineMeta{{Kind: model.LineSelected, Prefix: 10}},
+			wantOut: "abc\n",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := printer.New(&buf, tt.color)
+
+			require.NoError(t, p.Print(tt.task, tt.output, tt.meta))
+			require.NoError(t, p.Flush())
+
+			require.Equal(t, tt.wantOut, buf.String())
+		})
+	}
+}
+
+func TestPrintSeparators(t *testing.T) {
+	custom := "=="
+	type taskOutput struct {
+		fileName string
+		output   []string
+		meta     []model.LineMeta
+		carry    *model.ContextCarry // --follow: задание продолжает вывод файла
+	}
+	twoFiles := []taskOutput{
+		{
+			fileName: "f1",
+			output:   []string{"a", "--", "d", "a"},
+			meta: []model.LineMeta{
+				{Kind: model.LineSelected},
+				{Kind: model.LineGroupSep},
+				{Kind: model.LineContext},
+				{Kind: model.LineSelected},
+			},
+		},
+		{fileName: "f2", output: []string{}},
+		{
+			fileName: "f3",
+			output:   []string{"a"},
+			meta:     []model.LineMeta{{Kind: model.LineSelected}},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		gp      model.GrepParam
+		tasks   []taskOutput
+		wantOut string
+	}{
+		{
+			name:    "Positive - default separator between groups and between files",
+			gp:      model.GrepParam{CtxBefore: 1},
+			tasks:   twoFiles,
+			wantOut: "a\n--\nd\na\n--\na\n",
+		},
+		{
+			name:    "Positive - custom separator",
+			gp:      model.GrepParam{CtxBefore: 1, GroupSeparator: &custom},
+			tasks:   twoFiles,
+			wantOut: "a\n==\nd\na\n==\na\n",
+		},
+		{
+			name:    "Positive - no separator",
+			gp:      model.GrepParam{CtxBefore: 1, NoGroupSeparator: true},
+			tasks:   twoFiles,
+			wantOut: "a\nd\na\na\n",
+		},
+		{
+			name:    "Positive - no separator between files without context",
+			gp:      model.GrepParam{},
+			tasks:   []taskOutput{{fileName: "f1", output: []string{"a"}}, {fileName: "f2", output: []string{"b"}}},
+			wantOut: "a\nb\n",
+		},
+		{
+			name:    "Positive - heading",
+			gp:      model.GrepParam{CtxBefore: 1, PrintFileName: true, Heading: true},
+			tasks:   twoFiles,
+			wantOut: "f1\na\n--\nd\na\n\nf3\na\n",
+		},
+		{
+			name: "Positive - --follow task continues the output of the same file without a separator",
+			gp:   model.GrepParam{CtxAfter: 1, PrintFileName: true, Heading: true},
+			tasks: []taskOutput{
+				{fileName: "f1", output: []string{"a"}, meta: []model.LineMeta{{Kind: model.LineSelected}}, carry: &model.ContextCarry{}},
+				{fileName: "f1", output: []string{"b"}, meta: []model.LineMeta{{Kind: model.LineContext}}, carry: &model.ContextCarry{After: 1, LastPrinted: 1}},
+			},
+			wantOut: "f1\na\nb\n",
+		},
+		{
+			name: "Positive - --follow task after another file, separator isn't doubled",
+			gp:   model.GrepParam{CtxBefore: 1},
+			tasks: []taskOutput{
+				{fileName: "f1", output: []string{"a"}, meta: []model.LineMeta{{Kind: model.LineSelected}}, carry: &model.ContextCarry{}},
+				{fileName: "f2", output: []string{"c"}, meta: []model.LineMeta{{Kind: model.LineSelected}}, carry: &model.ContextCarry{}},
+				{
+					fileName: "f1",
+					output:   []string{"--", "e", "f"},
+					meta:     []model.LineMeta{{Kind: model.LineGroupSep}, {Kind: model.LineContext}, {Kind: model.LineSelected}},
+					carry:    &model.ContextCarry{Before: 1, LastPrinted: 1},
+				},
+			},
+			wantOut: "a\n--\nc\n--\ne\nf\n",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := printer.New(&buf, false)
+
+			for _, v := range tt.tasks {
+				task := &model.TaskDTO{FileName: v.fileName, GP: tt.gp, Carry: v.carry}
+				require.NoError(t, p.Print(task, v.output, v.meta))
+			}
+			require.NoError(t, p.Flush())
+
+			require.Equal(t, tt.wantOut, buf.String())
+		})
+	}
+}
+
+func TestPrintNode(t *testing.T) {
+	gp := model.GrepParam{CtxAfter: 1, PrintFileName: true, Remote: true}
+	task := &model.TaskDTO{FileName: "*.log", Paths: []string{"*.log"}, GP: gp}
+	output := []string{"a.log:x", "a.log-y", "--", "b.log:x"}
+	meta := []model.LineMeta{
+		{Kind: model.LineSelected, Prefix: 6},
+		{Kind: model.LineContext, Prefix: 6},
+		{Kind: model.LineGroupSep},
+		{Kind: model.LineSelected, Prefix: 6},
+	}
+
+	var buf bytes.Buffer
+	p := printer.New(&buf, false)
+	require.NoError(t, p.PrintNode("node1", task, output, meta))
+	require.NoError(t, p.PrintNode("node2", task, output[3:], meta[3:]))
+	require.NoError(t, p.Flush())
+
+	// разделители групп и файлов именем ноды не помечаются
+	require.Equal(t, "node1:a.log:x\nnode1:a.log-y\n--\nnode1:b.log:x\n--\nnode2:b.log:x\n", buf.String())
+}
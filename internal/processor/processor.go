@@ -3,96 +3,257 @@ package processor
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"log"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
 	"github.com/cespare/xxhash/v2"
 )
 
-type Processor struct{}
+// StdinName - имя, под которым stdin выводится в -l/-L и с -H
+const StdinName = model.StdinName
+
+// Processor выполняет задания slave-ноды; Root и Node нужны только для заданий --remote с путями вместо строк
+type Processor struct {
+	Root string // каталог, за пределы которого не выходят пути заданий --remote; пусто - такие задания запрещены
+	Node string // имя ноды для результатов
+}
 
 func (p Processor) ProcessInput(ctx context.Context, task *model.SlaveTask) *model.SlaveResult {
+	if len(task.Paths) != 0 {
+		return p.processPaths(ctx, task)
+	}
+
 	result := model.SlaveResult{
 		TaskID: task.TaskID,
+		Node:   p.Node,
 	}
+	m, err := newMatcher(&task.GP)
+	if err != nil {
+		log.Printf("problem with pattern %q: %v", task.GP.Pattern, err)
+		result.Output = []string{}
+		finishResult(ctx, &result, &task.GP, 0)
+		return &result
+	}
+
+	var selected int
+	result.Output, result.Meta, selected, result.Carry = processTask(ctx, task, m)
+	if fileMatched(ctx, &task.GP, result.Output) {
+		result.Matched = 1
+	}
+	finishResult(ctx, &result, &task.GP, selected)
+
+	return &result
+}
+
+// processTask ищет совпадения в строках одного входа и возвращает вывод, его разметку, кол-во выбранных строк
+// и, если задание пришло с Carry, состояние вывода контекста на его конец; m - паттерн задания, скомпилированный
+// один раз на все входы задания
+func processTask(ctx context.Context, task *model.SlaveTask, m *matcher) ([]string, []model.LineMeta, int, *model.ContextCarry) {
+	// двоичный вход: при --binary-files=without-match считаем, что совпадений в нем нет
+	input := task.Input
+	binary := task.Binary && task.GP.BinaryFiles != model.BinaryText
+	if binary && task.GP.BinaryFiles == model.BinaryWithoutMatch {
+		input = nil
+	}
+	// --follow: строки конца предыдущего задания уже искались - кроме вывода контекста они не нужны
+	fresh := input
+	if task.Carry != nil {
+		fresh = input[min(task.Carry.Before, len(input)):]
+	}
+
+	// у stdin нет имени файла - выводим его под --label или "(standard input)"
+	name := task.GP.InputName(task.FileName)
 
 	// считаем метчи или выводим метчи
-	switch task.GP.CountFound {
-	case true:
-		res := countMatchingLines(ctx, task.Input, task.FileName, &task.GP)
+	switch {
+	case task.GP.FilesWithMatch || task.GP.FilesWithoutMatch:
+		res, selected := listFileName(ctx, fresh, name, &task.GP, m)
 		if res == "" {
-			result.Output = []string{}
-		} else {
-			result.Output = []string{res}
+			return []string{}, nil, selected, nil
 		}
+		return []string{res}, nil, selected, nil
+
+	case task.GP.CountFound:
+		res, selected := countMatchingLines(ctx, fresh, name, &task.GP, m)
+		if res == "" {
+			return []string{}, nil, selected, nil
+		}
+		return []string{res}, nil, selected, nil
+
+	case binary: // вместо строк двоичного файла - только сообщение о совпадении
+		if hasSelected(ctx, fresh, m) && maxCount(&task.GP) != 0 {
+			return []string{fmt.Sprintf("Binary file %s matches", name)}, []model.LineMeta{{Kind: model.LineSelected}}, 1, nil
+		}
+		return []string{}, []model.LineMeta{}, 0, nil
 
 	default:
-		result.Output = getMatchingLines(ctx, task.Input, task.FileName, &task.GP)
+		return getMatchingLines(ctx, input, name, &task.GP, m, newLinePos(task), task.Carry)
 	}
+}
 
-	// считаем общий хеш
-	result.HashSumm = hasher(ctx, result.Output)
+// finishResult дополняет результат счетчиком для -m и хешем для кворума
+func finishResult(ctx context.Context, result *model.SlaveResult, gp *model.GrepParam, selected int) {
+	// кол-во выбранных строк нужно мастеру только для обрезки результата по -m, разметка - еще и для подсветки
+	if gp.MaxCount != nil {
+		result.Selected = selected
+	}
+	// --follow: по разметке мастер узнает разделитель групп в начале вывода, если его уже вывел сам
+	if !gp.NeedsMeta() && result.Carry == nil {
+		result.Meta = nil
+	}
 
-	return &result
+	// считаем общий хеш - разметка тоже входит в него, чтобы кворум подтверждал и ее
+	result.HashSumm = hasher(ctx, result.Output, result.Meta)
 }
 
-func countMatchingLines(ctx context.Context, input []string, fileName string, gp *model.GrepParam) string {
-	result := ""
+func countMatchingLines(ctx context.Context, input []string, fileName string, gp *model.GrepParam, m *matcher) (string, int) {
 	counter := 0
+	limit := maxCount(gp)
 	for _, v := range input {
+		if counter == limit { // -m: дальше файл не читаем
+			break
+		}
 		select {
 		case <-ctx.Done():
-			return ""
+			return "", 0
 		default:
-			match, err := findMatch(gp, v)
-			if err != nil {
-				log.Printf("problem with pattern %q: %v", gp.Pattern, err)
-				return result
-			}
-			if match {
+			if m.match(v) {
 				counter++
 			}
 		}
 	}
 
-	switch {
-	case gp.PrintFileName:
-		result = fmt.Sprintf("%s:%d", fileName, counter)
-	default:
-		result = fmt.Sprint(counter)
+	return gp.CountLine(fileName, counter), counter
+}
+
+// fileMatched сообщает, что файла нет в выводе -L потому, что в нем есть выбранные строки: по ним мастер
+// считает код возврата, ведь сам вывод -L состоит только из файлов без совпадений
+func fileMatched(ctx context.Context, gp *model.GrepParam, output []string) bool {
+	return gp.FilesWithoutMatch && len(output) == 0 && ctx.Err() == nil && maxCount(gp) != 0
+}
+
+// listFileName возвращает имя файла для -l/-L или "", если файл выводить не нужно;
+// чтение входа прекращается на первой выбранной строке
+func listFileName(ctx context.Context, input []string, fileName string, gp *model.GrepParam, m *matcher) (string, int) {
+	found := hasSelected(ctx, input, m)
+	if ctx.Err() != nil || found != gp.FilesWithMatch || maxCount(gp) == 0 {
+		return "", 0
 	}
-	return result
+	return fileName, 1
 }
 
-func getMatchingLines(ctx context.Context, input []string, fileName string, gp *model.GrepParam) []string {
+// hasSelected сообщает, есть ли во входе хотя бы одна выбранная строка; чтение прекращается на первой из них
+func hasSelected(ctx context.Context, input []string, m *matcher) bool {
+	for _, v := range input {
+		select {
+		case <-ctx.Done():
+			return false
+		default:
+			if m.match(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getMatchingLines выводит выбранные строки и их контекст. carry != nil - задание --follow продолжает вывод
+// предыдущего задания того же файла: первые carry.Before строк входа только дополняют контекст до совпадения,
+// а состояние вывода на конец задания возвращается последним значением
+func getMatchingLines(ctx context.Context, input []string, fileName string, gp *model.GrepParam, m *matcher, pos linePos,
+	carry *model.ContextCarry) ([]string, []model.LineMeta, int, *model.ContextCarry) {
 	result := []string{}
+	meta := []model.LineMeta{}
 	lineN := 1
 	beforeBuf := make([]string, 0, gp.CtxBefore)
 	isCtxZone := false
 	lastPrintedN := 0
+	hasPrinted := false // lastPrintedN может быть и <= 0 - строка предыдущего задания
 	isMatch := false
 	isPrinted := make(map[int]struct{})
 	afterCount := 0
+	selected := 0
+
+	lead := 0
+	if carry != nil {
+		lead = carry.Before
+		afterCount = carry.After
+		isCtxZone = afterCount > 0
+		if carry.LastPrinted > 0 {
+			hasPrinted = true
+			lastPrintedN = carry.LastPrinted - pos.baseLine
+		}
+	}
+	carryOut := func() *model.ContextCarry {
+		if carry == nil {
+			return nil
+		}
+		next := &model.ContextCarry{After: afterCount}
+		if hasPrinted {
+			next.LastPrinted = pos.line(lastPrintedN)
+		}
+		return next
+	}
+
+	// -m: после limit выбранных строк выводится только завершающий контекст
+	limit := maxCount(gp)
+	stopped := limit == 0
+	if stopped {
+		return result, meta, selected, carryOut()
+	}
+
+	appendLine := func(line string, n int, kind model.LineKind) {
+		var spans []model.Span
+		if (gp.Color && (kind == model.LineSelected) != gp.InvertResult) || (gp.Column && kind == model.LineSelected) {
+			spans = m.spans(line)
+		}
+		// --column: колонка первого совпадения есть только у выбранных строк; у строки без совпадений(-v) это 1
+		col := 0
+		if gp.Column && kind == model.LineSelected {
+			col = 1
+			if len(spans) != 0 {
+				col = spans[0].Start + 1
+			}
+		}
+
+		out := normalizeLine(gp, line, fileName, pos.line(n), pos.offset(n), col)
+		lm := model.LineMeta{Kind: kind, Prefix: len(out) - len(line)}
+		// как и GNU grep, подсвечиваем совпадения в выбранных строках, а при -v - в строках контекста
+		if gp.Color && (kind == model.LineSelected) != gp.InvertResult {
+			lm.Spans = spans
+		}
+		result = append(result, out)
+		meta = append(meta, lm)
+	}
 
 	var withCTX bool
 	if gp.CtxAfter != 0 || gp.CtxBefore != 0 {
 		withCTX = true
 	}
 
-	var err error
-
 	for _, line := range input {
 		select {
 		case <-ctx.Done():
-			return []string{}
+			return []string{}, nil, 0, nil
 		default: // всю дефолтную ветку можно вынести в отдельную функцию внутри этой функции для читабельности
-			isMatch, err = findMatch(gp, line)
-			if err != nil {
-				log.Printf("problem with pattern %q: %v", gp.Pattern, err)
-				return result
+			if lineN <= lead { // конец предыдущего задания - только в буфер контекста до совпадения
+				if gp.CtxBefore > 0 {
+					if len(beforeBuf) == gp.CtxBefore {
+						beforeBuf = beforeBuf[1:]
+					}
+					beforeBuf = append(beforeBuf, line)
+				}
+				lineN++
+				continue
+			}
+			isMatch = m.match(line)
+			if stopped { // лимит -m достигнут - совпадения печатаются только как контекст
+				isMatch = false
 			}
 
 			switch withCTX {
@@ -101,14 +262,16 @@ func getMatchingLines(ctx context.Context, input []string, fileName string, gp *
 					if !isCtxZone {
 						// разбираемся с BEFORE и вставляем разделитель если надо
 						j := lineN - len(beforeBuf)
-						if j-lastPrintedN > 1 && lastPrintedN != 0 {
-							result = append(result, "--")
+						if j-lastPrintedN > 1 && hasPrinted {
+							// разделитель всегда "--" - при --group-separator/--no-group-separator его по разметке заменит мастер
+							result = append(result, model.DefaultGroupSeparator)
+							meta = append(meta, model.LineMeta{Kind: model.LineGroupSep})
 						}
 						for i := range beforeBuf {
-							if _, ok := isPrinted[j]; !ok {
-								result = append(result, normalizeLine(gp, beforeBuf[i], fileName, j))
+							if _, ok := isPrinted[j]; !ok && j > lastPrintedN {
+								appendLine(beforeBuf[i], j, model.LineContext)
 								isPrinted[j] = struct{}{}
-								lastPrintedN = j
+								lastPrintedN, hasPrinted = j, true
 							}
 							j++
 						}
@@ -116,14 +279,21 @@ func getMatchingLines(ctx context.Context, input []string, fileName string, gp *
 
 					// обработка самой isMatch-строки
 					if _, ok := isPrinted[lineN]; !ok {
-						result = append(result, normalizeLine(gp, line, fileName, lineN))
-						lastPrintedN = lineN
+						appendLine(line, lineN, model.LineSelected)
+						lastPrintedN, hasPrinted = lineN, true
 						isPrinted[lineN] = struct{}{}
 						if gp.CtxAfter > 0 {
 							isCtxZone = true
 						}
 						afterCount = gp.CtxAfter
 					}
+					selected++
+					if selected == limit {
+						stopped = true
+						if afterCount == 0 {
+							return result, meta, selected, carryOut()
+						}
+					}
 					lineN++
 					continue
 				}
@@ -131,13 +301,16 @@ func getMatchingLines(ctx context.Context, input []string, fileName string, gp *
 				// разбираемся с AFTER
 				if afterCount > 0 {
 					if _, ok := isPrinted[lineN]; !ok {
-						result = append(result, normalizeLine(gp, line, fileName, lineN))
-						lastPrintedN = lineN
+						appendLine(line, lineN, model.LineContext)
+						lastPrintedN, hasPrinted = lineN, true
 						isPrinted[lineN] = struct{}{}
 					}
 					afterCount--
 					if afterCount == 0 {
 						isCtxZone = false
+						if stopped {
+							return result, meta, selected, carryOut()
+						}
 					}
 				}
 
@@ -150,7 +323,11 @@ func getMatchingLines(ctx context.Context, input []string, fileName string, gp *
 				}
 			default:
 				if isMatch {
-					result = append(result, normalizeLine(gp, line, fileName, lineN))
+					appendLine(line, lineN, model.LineSelected)
+					selected++
+					if selected == limit {
+						return result, meta, selected, carryOut()
+					}
 				}
 			}
 
@@ -158,49 +335,171 @@ func getMatchingLines(ctx context.Context, input []string, fileName string, gp *
 		}
 	}
 
-	return result
+	return result, meta, selected, carryOut()
 }
 
-// учесть что нужно делать префикс имени файла + нумерация строк
-func normalizeLine(SP *model.GrepParam, line, fileName string, n int) string {
-	switch {
-	case SP.PrintFileName && SP.EnumLine:
-		return fmt.Sprintf("%s:%d:%s", fileName, n, line)
-	case SP.EnumLine:
-		return fmt.Sprintf("%d:%s", n, line)
-	case SP.PrintFileName:
-		return fmt.Sprintf("%s:%s", fileName, line)
-	default:
+// maxCount возвращает лимит выбранных строк по -m или -1, если лимита нет
+func maxCount(gp *model.GrepParam) int {
+	if gp.MaxCount == nil {
+		return -1
+	}
+	return *gp.MaxCount
+}
+
+// normalizeLine добавляет к строке префикс в порядке GNU grep: имя файла, номер строки, байтовое смещение;
+// col > 0 - колонка первого совпадения(--column), она идет последней. При --heading имя файла
+// выводит мастер один раз перед строками файла
+func normalizeLine(SP *model.GrepParam, line, fileName string, n int, offset int64, col int) string {
+	withName := SP.PrintFileName && !SP.Heading
+	if !withName && !SP.EnumLine && !SP.ByteOffset && col == 0 {
 		return line
 	}
+
+	var sb strings.Builder
+	if withName {
+		sb.WriteString(fileName)
+		sb.WriteString(nameSep(SP, ':'))
+	}
+	if SP.EnumLine {
+		sb.WriteString(strconv.Itoa(n))
+		sb.WriteByte(':')
+	}
+	if SP.ByteOffset {
+		sb.WriteString(strconv.FormatInt(offset, 10))
+		sb.WriteByte(':')
+	}
+	if col > 0 {
+		sb.WriteString(strconv.Itoa(col))
+		sb.WriteByte(':')
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
+
+// linePos переводит порядковый номер строки задания(с 1) в номер строки и байтовое смещение во всем входе
+type linePos struct {
+	baseLine   int
+	baseOffset int64
+	offsets    []int64
+}
+
+func newLinePos(task *model.SlaveTask) linePos {
+	pos := linePos{
+		baseLine:   task.BaseLine,
+		baseOffset: task.BaseOffset,
+		offsets:    task.Offsets,
+	}
+	// мастер не прислал смещения(или они не совпадают со строками) - считаем, что строки разделены одним байтом
+	if task.GP.ByteOffset && len(pos.offsets) != len(task.Input) {
+		pos.offsets = make([]int64, len(task.Input))
+		var off int64
+		for i, line := range task.Input {
+			pos.offsets[i] = off
+			off += int64(len(line)) + 1
+		}
+	}
+	return pos
+}
+
+func (lp linePos) line(n int) int {
+	return lp.baseLine + n
+}
+
+func (lp linePos) offset(n int) int64 {
+	if n < 1 || n > len(lp.offsets) {
+		return lp.baseOffset
+	}
+	return lp.baseOffset + lp.offsets[n-1]
+}
+
+// nameSep - разделитель после имени файла: при -Z это NUL-байт
+func nameSep(gp *model.GrepParam, sep byte) string {
+	if gp.NullName {
+		return "\x00"
+	}
+	return string(sep)
+}
+
+// matcher - паттерн задания: регулярка компилируется один раз на задание и живет, пока оно выполняется,
+// поэтому разные паттерны от мастеров не накапливаются в памяти slave-ноды
+type matcher struct {
+	gp      *model.GrepParam
+	re      *regexp.Regexp // nil при -F
+	longest *regexp.Regexp // копия re для подсветки: самое длинное из совпадений, начинающихся в одной позиции
 }
 
-func findMatch(gp *model.GrepParam, line string) (bool, error) {
-	if gp.IgnoreCase { //-i
+func newMatcher(gp *model.GrepParam) (*matcher, error) {
+	m := &matcher{gp: gp}
+	if gp.ExactMatch { //-F
+		return m, nil
+	}
+	raw := gp.Pattern
+	if gp.NullData { // -z: запись может содержать переводы строк, и '.' должна их захватывать, как в GNU grep
+		raw = "(?s)" + raw
+	}
+	re, err := regexp.Compile(raw)
+	if err != nil {
+		return nil, err
+	}
+	m.re = re
+	m.longest = re.Copy()
+	m.longest.Longest()
+	return m, nil
+}
+
+// match сообщает, выбрана ли строка - с учетом -v
+func (m *matcher) match(line string) bool {
+	if m.gp.IgnoreCase { //-i
 		line = strings.ToLower(line)
 	}
 
 	var res bool
-
 	switch {
-	case gp.ExactMatch: //-F
-		res = strings.Contains(line, gp.Pattern)
+	case m.re == nil: //-F
+		res = strings.Contains(line, m.gp.Pattern)
 	default:
-		pattern, err := regexp.Compile(gp.Pattern)
-		if err != nil {
-			return false, err
-		}
-		res = pattern.MatchString(line)
+		res = m.re.MatchString(line)
 	}
 
-	if gp.InvertResult { //-v - инвертирвоание результата
+	if m.gp.InvertResult { //-v - инвертирвоание результата
 		res = !res
 	}
+	return res
+}
 
-	return res, nil
+// spans возвращает границы всех непустых совпадений паттерна в строке - без учета -v
+func (m *matcher) spans(line string) []model.Span {
+	var spans []model.Span
+
+	switch {
+	case m.re == nil: //-F
+		if m.gp.Pattern == "" {
+			return nil
+		}
+		if m.gp.IgnoreCase {
+			line = strings.ToLower(line)
+		}
+		for start := 0; ; {
+			i := strings.Index(line[start:], m.gp.Pattern)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, model.Span{Start: start + i, End: start + i + len(m.gp.Pattern)})
+			start += i + len(m.gp.Pattern)
+		}
+	default:
+		// как и GNU grep, подсвечиваем самое длинное из совпадений, начинающихся в одной позиции
+		for _, loc := range m.longest.FindAllStringIndex(line, -1) {
+			if loc[0] != loc[1] {
+				spans = append(spans, model.Span{Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+
+	return spans
 }
 
-func hasher(ctx context.Context, input []string) uint64 {
+func hasher(ctx context.Context, input []string, meta []model.LineMeta) uint64 {
 	hs := xxhash.New()
 	for _, s := range input {
 		select {
@@ -210,5 +509,17 @@ func hasher(ctx context.Context, input []string) uint64 {
 			_, _ = hs.WriteString(s)
 		}
 	}
+
+	var buf []byte
+	for _, m := range meta {
+		buf = append(buf[:0], byte(m.Kind))
+		buf = binary.AppendUvarint(buf, uint64(m.Prefix))
+		buf = binary.AppendUvarint(buf, uint64(len(m.Spans)))
+		for _, sp := range m.Spans {
+			buf = binary.AppendUvarint(buf, uint64(sp.Start))
+			buf = binary.AppendUvarint(buf, uint64(sp.End))
+		}
+		_, _ = hs.Write(buf)
+	}
 	return hs.Sum64()
 }
@@ -2,6 +2,10 @@ package processor_test
 
 import (
 	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
@@ -276,6 +280,516 @@ func TestProcessInput(t *testing.T) {
 			},
 			ctx: context.Background(),
 		},
+		{
+			name: "Positive - max count",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "abc",
+					ExactMatch: true,
+					MaxCount:   intPtr(2),
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"abcabcabc123", "abcabc123"},
+				HashSumm: hasherMeta(t, []string{"abcabcabc123", "abcabc123"}, []model.LineMeta{{Kind: model.LineSelected}, {Kind: model.LineSelected}}),
+				Selected: 2,
+				Meta:     []model.LineMeta{{Kind: model.LineSelected}, {Kind: model.LineSelected}},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - max count & trailing ctx after", // совпадения после лимита выводятся как контекст
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "abc",
+					ExactMatch: true,
+					MaxCount:   intPtr(1),
+					CtxAfter:   1,
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"abcabcabc123", "abcabc123"},
+				HashSumm: hasherMeta(t, []string{"abcabcabc123", "abcabc123"}, []model.LineMeta{{Kind: model.LineSelected}, {Kind: model.LineContext}}),
+				Selected: 1,
+				Meta:     []model.LineMeta{{Kind: model.LineSelected}, {Kind: model.LineContext}},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - max count & count lines",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "abc",
+					ExactMatch: true,
+					CountFound: true,
+					MaxCount:   intPtr(2),
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"2"},
+				HashSumm: hasher(t, []string{"2"}),
+				Selected: 2,
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - max count & invert result",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:      "abc$",
+					InvertResult: true,
+					MaxCount:     intPtr(1),
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"abcabcabc123"},
+				HashSumm: hasherMeta(t, []string{"abcabcabc123"}, []model.LineMeta{{Kind: model.LineSelected}}),
+				Selected: 1,
+				Meta:     []model.LineMeta{{Kind: model.LineSelected}},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - max count zero",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:  "abc",
+					MaxCount: intPtr(0),
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{},
+				HashSumm: hasher(t, []string{}),
+				Meta:     []model.LineMeta{},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - files with matches",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:        "abc",
+					FilesWithMatch: true,
+					CountFound:     true, // -l важнее -c
+				},
+				Input:    inputArray,
+				FileName: "someName",
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"someName"},
+				HashSumm: hasher(t, []string{"someName"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - files with matches from stdin",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:        "^123$",
+					FilesWithMatch: true,
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{processor.StdinName},
+				HashSumm: hasher(t, []string{processor.StdinName}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - files without match",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:           "xyz",
+					FilesWithoutMatch: true,
+				},
+				Input:    inputArray,
+				FileName: "someName",
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"someName"},
+				HashSumm: hasher(t, []string{"someName"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - files without match & file matches",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:           "abc",
+					FilesWithoutMatch: true,
+				},
+				Input:    inputArray,
+				FileName: "someName",
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{},
+				Matched:  1,
+				HashSumm: hasher(t, []string{}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - files without match & -m 0",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:           "abc",
+					FilesWithoutMatch: true,
+					MaxCount:          intPtr(0),
+				},
+				Input:    inputArray,
+				FileName: "someName",
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{},
+				HashSumm: hasher(t, []string{}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - color spans regexp",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:  "ab|abc",
+					EnumLine: true,
+					Color:    true,
+				},
+				Input: []string{"xabcab", "123"},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID: "testTask",
+				Output: []string{"1:xabcab"},
+				HashSumm: hasherMeta(t, []string{"1:xabcab"}, []model.LineMeta{
+					{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 1, End: 4}, {Start: 4, End: 6}}},
+				}),
+				Meta: []model.LineMeta{
+					{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 1, End: 4}, {Start: 4, End: 6}}},
+				},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - color spans exact match ignore case & ctx",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:       "abc",
+					ExactMatch:    true,
+					IgnoreCase:    true,
+					PrintFileName: true,
+					CtxAfter:      1,
+					Color:         true,
+				},
+				Input:    []string{"ABCxabc", "123"},
+				FileName: "f",
+			},
+			wantRes: &model.SlaveResult{
+				TaskID: "testTask",
+				Output: []string{"f:ABCxabc", "f:123"},
+				HashSumm: hasherMeta(t, []string{"f:ABCxabc", "f:123"}, []model.LineMeta{
+					{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 0, End: 3}, {Start: 4, End: 7}}},
+					{Kind: model.LineContext, Prefix: 2},
+				}),
+				Meta: []model.LineMeta{
+					{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 0, End: 3}, {Start: 4, End: 7}}},
+					{Kind: model.LineContext, Prefix: 2},
+				},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - color spans invert result go to context lines",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:      "abc",
+					InvertResult: true,
+					CtxBefore:    1,
+					Color:        true,
+				},
+				Input: []string{"abc", "123"},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID: "testTask",
+				Output: []string{"abc", "123"},
+				HashSumm: hasherMeta(t, []string{"abc", "123"}, []model.LineMeta{
+					{Kind: model.LineContext, Spans: []model.Span{{Start: 0, End: 3}}},
+					{Kind: model.LineSelected},
+				}),
+				Meta: []model.LineMeta{
+					{Kind: model.LineContext, Spans: []model.Span{{Start: 0, End: 3}}},
+					{Kind: model.LineSelected},
+				},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - binary file matches",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:  "abc",
+					EnumLine: true,
+				},
+				Input:    []string{"abc\x00def", "abc"},
+				FileName: "someName",
+				Binary:   true,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"Binary file someName matches"},
+				HashSumm: hasher(t, []string{"Binary file someName matches"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - binary file processed as text",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:     "def",
+					BinaryFiles: model.BinaryText,
+				},
+				Input:  []string{"abc\x00def", "abc"},
+				Binary: true,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"abc\x00def"},
+				HashSumm: hasher(t, []string{"abc\x00def"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - binary file without match & count lines",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:     "abc",
+					CountFound:  true,
+					BinaryFiles: model.BinaryWithoutMatch,
+				},
+				Input:  []string{"abc\x00def", "abc"},
+				Binary: true,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"0"},
+				HashSumm: hasher(t, []string{"0"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - binary file doesn't match",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern: "xyz",
+				},
+				Input:  []string{"abc\x00def", "abc"},
+				Binary: true,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{},
+				HashSumm: hasher(t, []string{}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - null after file name in count output",
+			task: &model.SlaveTask{
+				TaskID:   "testTask",
+				FileName: "f1",
+				GP: model.GrepParam{
+					Pattern:       "^abc",
+					CountFound:    true,
+					PrintFileName: true,
+					NullName:      true,
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"f1\x003"},
+				HashSumm: hasher(t, []string{"f1\x003"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - null-delimited records, dot matches newline",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:  "c.1",
+					NullData: true,
+					EnumLine: true,
+				},
+				Input: []string{"abc\n123", "abc123", "xyz"},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"1:abc\n123"},
+				HashSumm: hasherMeta(t, []string{"1:abc\n123"}, []model.LineMeta{{Kind: model.LineSelected, Prefix: 2}}),
+				Meta:     []model.LineMeta{{Kind: model.LineSelected, Prefix: 2}},
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - byte offsets from master",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "^abc1",
+					ByteOffset: true,
+					EnumLine:   true,
+				},
+				Input:   inputArray,
+				Offsets: []int64{0, 14, 25, 32},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"3:25:abc123"},
+				HashSumm: hasher(t, []string{"3:25:abc123"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - byte offsets without offsets from master, task starts mid-input",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "^abc1",
+					ByteOffset: true,
+					EnumLine:   true,
+				},
+				Input:      inputArray,
+				BaseOffset: 100,
+				BaseLine:   10,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"13:123:abc123"},
+				HashSumm: hasher(t, []string{"13:123:abc123"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - column of first match in selected lines only",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:    "(?i)c1",
+					Column:     true,
+					IgnoreCase: true,
+					CtxBefore:  1,
+				},
+				Input: []string{"ABC", "xyzABC123", "123"},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"ABC", "6:xyzABC123"},
+				HashSumm: hasher(t, []string{"ABC", "6:xyzABC123"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - stdin file name with -H",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:       "^abc1",
+					PrintFileName: true,
+					EnumLine:      true,
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"(standard input):3:abc123"},
+				HashSumm: hasher(t, []string{"(standard input):3:abc123"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - stdin label in count output",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP: model.GrepParam{
+					Pattern:       "^abc",
+					CountFound:    true,
+					PrintFileName: true,
+					Label:         "foo",
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"foo:3"},
+				HashSumm: hasher(t, []string{"foo:3"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Positive - file name left to master with --heading",
+			task: &model.SlaveTask{
+				TaskID:   "testTask",
+				FileName: "someName",
+				GP: model.GrepParam{
+					Pattern:       "^abc1",
+					PrintFileName: true,
+					Heading:       true,
+					EnumLine:      true,
+				},
+				Input: inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{"3:abc123"},
+				HashSumm: hasher(t, []string{"3:abc123"}),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Negative - pattern doesn't compile",
+			task: &model.SlaveTask{
+				TaskID: "testTask",
+				GP:     model.GrepParam{Pattern: "abc("},
+				Input:  inputArray,
+			},
+			wantRes: &model.SlaveResult{
+				TaskID:   "testTask",
+				Output:   []string{},
+				HashSumm: hasher(t, []string{}),
+			},
+			ctx: context.Background(),
+		},
 	}
 
 	for _, tt := range cases {
@@ -289,6 +803,128 @@ func TestProcessInput(t *testing.T) {
 	}
 }
 
+// TestProcessInputCarry: файл, разбитый на два задания --follow, дает тот же вывод, что и весь файл одним заданием,
+// где бы ни прошел стык
+func TestProcessInputCarry(t *testing.T) {
+	input := []string{"a", "x1", "b", "c", "d", "x2", "e", "f", "g", "h", "x3", "x4", "i"}
+	cases := []struct {
+		name   string
+		before int
+		after  int
+	}{
+		{name: "Positive - -B only", before: 2},
+		{name: "Positive - -A only", after: 2},
+		{name: "Positive - -A and -B", before: 1, after: 1},
+		{name: "Positive - context longer than the gap between matches", before: 3, after: 3},
+	}
+
+	for _, tt := range cases {
+		gp := model.GrepParam{Pattern: "x", EnumLine: true, CtxBefore: tt.before, CtxAfter: tt.after}
+		whole := processor.Processor{}.ProcessInput(context.Background(), &model.SlaveTask{TaskID: "whole", GP: gp, Input: input})
+		for split := 1; split < len(input); split++ {
+			t.Run(fmt.Sprintf("%s, split at %d", tt.name, split), func(t *testing.T) {
+				first := processor.Processor{}.ProcessInput(context.Background(), &model.SlaveTask{
+					TaskID: "first", GP: gp, Input: input[:split], Carry: &model.ContextCarry{},
+				})
+				require.NotNil(t, first.Carry)
+
+				// второе задание начинается с последних -B строк первого, как его собирает мастер
+				lead := min(tt.before, split)
+				carry := *first.Carry
+				carry.Before = lead
+				second := processor.Processor{}.ProcessInput(context.Background(), &model.SlaveTask{
+					TaskID: "second", GP: gp, Input: input[split-lead:], BaseLine: split - lead, Carry: &carry,
+				})
+				require.Equal(t, whole.Output, append(first.Output, second.Output...))
+			})
+		}
+	}
+}
+
+func TestProcessInputRemote(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "logs", "old"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "logs", "a.log"), []byte("abc1\nxyz\nabc2\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "logs", "b.log"), []byte("abc3\n"), 0o644))
+	outside := filepath.Join(t.TempDir(), "secret.log")
+	require.NoError(t, os.WriteFile(outside, []byte("abc secret\n"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "logs", "c.log")))
+
+	cases := []struct {
+		name        string
+		root        string
+		gp          model.GrepParam
+		paths       []string
+		wantOutput  []string
+		wantErrs    []string
+		wantMatched int
+	}{
+		{
+			name:       "Positive - glob across files",
+			root:       root,
+			gp:         model.GrepParam{Pattern: "abc", EnumLine: true, PrintFileName: true},
+			paths:      []string{"logs/*.log"},
+			wantOutput: []string{"logs/a.log:1:abc1", "logs/a.log:3:abc2", "logs/b.log:1:abc3"},
+			wantErrs:   []string{"logs/c.log: points outside of the node's root"},
+		},
+		{
+			name:       "Positive - -m shared between files",
+			root:       root,
+			gp:         model.GrepParam{Pattern: "abc", MaxCount: intPtr(2)},
+			paths:      []string{"logs/a.log", "logs/b.log"},
+			wantOutput: []string{"abc1", "abc2"},
+		},
+		{
+			name:       "Positive - -c per file",
+			root:       root,
+			gp:         model.GrepParam{Pattern: "abc", CountFound: true, PrintFileName: true},
+			paths:      []string{"logs/a.log", "logs/b.log"},
+			wantOutput: []string{"logs/a.log:2", "logs/b.log:1"},
+		},
+		{
+			name:        "Positive - -L counts files with matches",
+			root:        root,
+			gp:          model.GrepParam{Pattern: "xyz", FilesWithoutMatch: true},
+			paths:       []string{"logs/a.log", "logs/b.log"},
+			wantOutput:  []string{"logs/b.log"},
+			wantMatched: 1,
+		},
+		{
+			name:       "Negative - paths outside of root, directory and missing file",
+			root:       root,
+			gp:         model.GrepParam{Pattern: "abc"},
+			paths:      []string{"../x", "/etc/passwd", "logs/old", "logs/none.log"},
+			wantOutput: []string{},
+			wantErrs: []string{
+				"../x: path must be relative and stay inside the node's root",
+				"/etc/passwd: path must be relative and stay inside the node's root",
+				"logs/old: Is a directory",
+				"logs/none.log: No such file or directory",
+			},
+		},
+		{
+			name:       "Negative - node without root",
+			gp:         model.GrepParam{Pattern: "abc"},
+			paths:      []string{"logs/a.log"},
+			wantOutput: []string{},
+			wantErrs:   []string{"local file search is disabled on this node: it was started without --root"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			p := processor.Processor{Root: tt.root, Node: "node1"}
+			task := &model.SlaveTask{TaskID: "testTask", GP: tt.gp, Input: []string{}, Paths: tt.paths}
+
+			res := p.ProcessInput(context.Background(), task)
+			require.Equal(t, "node1", res.Node)
+			require.Equal(t, tt.wantOutput, res.Output)
+			require.Equal(t, tt.wantErrs, res.Errors)
+			require.Equal(t, tt.wantMatched, res.Matched)
+		})
+	}
+}
+
 func hasher(t *testing.T, input []string) uint64 {
 	t.Helper()
 	hs := xxhash.New()
@@ -299,3 +935,30 @@ func hasher(t *testing.T, input []string) uint64 {
 
 	return hs.Sum64()
 }
+
+// hasherMeta повторяет кодирование разметки строк в хеше результата
+func hasherMeta(t *testing.T, input []string, meta []model.LineMeta) uint64 {
+	t.Helper()
+	hs := xxhash.New()
+	for _, s := range input {
+		_, err := hs.WriteString(s)
+		require.NoError(t, err, "failed to write data to count hash")
+	}
+	for _, m := range meta {
+		buf := []byte{byte(m.Kind)}
+		buf = binary.AppendUvarint(buf, uint64(m.Prefix))
+		buf = binary.AppendUvarint(buf, uint64(len(m.Spans)))
+		for _, sp := range m.Spans {
+			buf = binary.AppendUvarint(buf, uint64(sp.Start))
+			buf = binary.AppendUvarint(buf, uint64(sp.End))
+		}
+		_, err := hs.Write(buf)
+		require.NoError(t, err, "failed to write line markup to count hash")
+	}
+
+	return hs.Sum64()
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+)
+
+// localFile - файл задания --remote: путь на диске и имя для вывода(относительно Root)
+type localFile struct {
+	path string
+	name string
+}
+
+// processPaths выполняет задание --remote: файлы по путям и glob-шаблонам задания ищутся на диске
+// самой slave-ноды внутри p.Root и обрабатываются по порядку так же, как если бы их прислал мастер.
+// Проблемы с отдельными путями не прерывают задание, а попадают в Errors результата
+func (p Processor) processPaths(ctx context.Context, task *model.SlaveTask) *model.SlaveResult {
+	result := model.SlaveResult{
+		TaskID: task.TaskID,
+		Node:   p.Node,
+		Output: []string{},
+		Meta:   []model.LineMeta{},
+	}
+
+	files, errs := resolvePaths(p.Root, task.Paths)
+	result.Errors = errs
+
+	gp := task.GP
+	opts := reader.Options{
+		NullData:          gp.NullData,
+		Offsets:           gp.ByteOffset,
+		Decompress:        gp.Decompress,
+		Encoding:          gp.Encoding,
+		MaxLineSize:       gp.MaxLineSize,
+		TruncateLongLines: gp.LongLines == model.LongLinesTruncate,
+		Archives:          gp.Archives,
+		MaxMemberSize:     gp.MaxMemberSize,
+		MaxArchiveSize:    gp.MaxArchiveSize,
+		MaxArchiveMembers: gp.MaxArchiveMembers,
+	}
+	pattern, err := newMatcher(&gp)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("problem with pattern %q: %v", gp.Pattern, err))
+		finishResult(ctx, &result, &gp, 0)
+		return &result
+	}
+	limit := maxCount(&gp)
+	withSep := (gp.CtxAfter > 0 || gp.CtxBefore > 0) && !gp.CountFound && !gp.FilesWithMatch && !gp.FilesWithoutMatch
+
+	selected := 0
+	for _, f := range files {
+		if ctx.Err() != nil || selected == limit {
+			break
+		}
+
+		members, err := reader.ReadMembers(f.path, opts)
+		if err != nil {
+			result.Errors = append(result.Errors, (&reader.FileError{Path: f.name, Err: err}).Error())
+			continue
+		}
+
+		// каждый файл архива(--archives) обрабатывается как отдельный файл "архив:путь"
+		for _, m := range members {
+			if ctx.Err() != nil || selected == limit {
+				break
+			}
+
+			// -m: лимит общий для всех файлов задания
+			sub := model.SlaveTask{
+				TaskID:   task.TaskID,
+				GP:       gp,
+				Input:    m.Lines,
+				FileName: m.Name(f.name),
+				Binary:   reader.IsBinary(m.Lines),
+				Offsets:  m.Offsets,
+			}
+			if limit >= 0 {
+				left := limit - selected
+				sub.GP.MaxCount = &left
+			}
+
+			output, meta, n, _ := processTask(ctx, &sub, pattern)
+			if fileMatched(ctx, &sub.GP, output) {
+				result.Matched++
+			}
+			if len(output) == 0 {
+				continue
+			}
+			if len(meta) != len(output) { // у -c и -l/-L разметки нет - каждая их строка относится к своему файлу
+				meta = make([]model.LineMeta, len(output))
+				for i := range meta {
+					meta[i].Kind = model.LineSelected
+				}
+			}
+			// группы контекста разных файлов разделяются так же, как группы внутри файла
+			if withSep && len(result.Output) != 0 {
+				result.Output = append(result.Output, model.DefaultGroupSeparator)
+				result.Meta = append(result.Meta, model.LineMeta{Kind: model.LineGroupSep})
+			}
+			result.Output = append(result.Output, output...)
+			result.Meta = append(result.Meta, meta...)
+			selected += n
+		}
+	}
+
+	finishResult(ctx, &result, &gp, selected)
+	return &result
+}
+
+// resolvePaths раскрывает пути и glob-шаблоны задания(в том числе с "**") внутри root. Пути должны быть относительными и не
+// выходить за root - в том числе через символические ссылки; каталоги и специальные файлы пропускаются
+func resolvePaths(root string, patterns []string) ([]localFile, []string) {
+	if root == "" {
+		return nil, []string{"local file search is disabled on this node: it was started without --root"}
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("invalid --root %q: %v", root, err)}
+	}
+
+	var files []localFile
+	var errs []string
+	for _, pattern := range patterns {
+		if !filepath.IsLocal(filepath.FromSlash(pattern)) {
+			errs = append(errs, fmt.Sprintf("%s: path must be relative and stay inside the node's root", pattern))
+			continue
+		}
+		matches, err := reader.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", pattern, err))
+			continue
+		}
+		if len(matches) == 0 {
+			errs = append(errs, (&reader.FileError{Path: pattern, Err: fs.ErrNotExist}).Error())
+			continue
+		}
+
+		for _, m := range matches {
+			name, _ := filepath.Rel(root, m)
+			realPath, err := filepath.EvalSymlinks(m)
+			if err != nil {
+				errs = append(errs, (&reader.FileError{Path: name, Err: err}).Error())
+				continue
+			}
+			if rel, err := filepath.Rel(realRoot, realPath); err != nil || !filepath.IsLocal(rel) {
+				errs = append(errs, fmt.Sprintf("%s: points outside of the node's root", name))
+				continue
+			}
+			info, err := os.Stat(realPath)
+			switch {
+			case err != nil:
+				errs = append(errs, (&reader.FileError{Path: name, Err: err}).Error())
+			case info.IsDir():
+				errs = append(errs, name+": Is a directory")
+			case info.Mode().IsRegular():
+				files = append(files, localFile{path: realPath, name: filepath.ToSlash(name)})
+			}
+		}
+	}
+	return files, errs
+}
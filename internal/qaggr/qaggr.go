@@ -4,7 +4,11 @@ package qaggr
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,13 +16,90 @@ import (
 )
 
 type taskTotals struct {
-	task  *model.MasterTask
-	votes int
-	data  []string
+	task     *model.MasterTask
+	votes    int
+	data     []string
+	selected int
+	matched  int
+	meta     []model.LineMeta
+	carry    *model.ContextCarry
 }
 
+// TaskResult - подтвержденный кворумом результат задания вместе с разметкой строк;
+// Node заполняется только при --no-quorum, когда результат принадлежит одной slave-ноде
+type TaskResult struct {
+	Task    *model.MasterTask
+	Node    string
+	Output  []string
+	Meta    []model.LineMeta
+	Matched int                 // -L: кол-во файлов задания с выбранными строками
+	Carry   *model.ContextCarry // --follow с -A/-B: состояние вывода контекста на конец задания
+}
+
+// ошибки заданий, по которым нет результата; в problems они приходят с именем файла задания
+var (
+	ErrNoQuorum = errors.New("slave-nodes didn't reach a quorum on the result")
+	ErrNoResult = errors.New("no slave-node returned a result")
+)
+
+// taskProblem - проблема с файлом задания в формате GNU grep("путь: причина")
+func taskProblem(task *model.MasterTask, err error) error {
+	return fmt.Errorf("%s: %w", task.Task.GP.InputName(task.Task.FileName), err)
+}
+
+// CollectAggregateResults возвращает только строки вывода подтвержденных кворумом заданий
 func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, tasks []*model.MasterTask, quorum int) ([][]string, error) {
-	quorumResults := make(map[string]*[]string, len(tasks))
+	results, _, err := CollectTaskResults(ctx, ch, tasks, quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	var resStrings [][]string
+	for _, v := range results {
+		resStrings = append(resStrings, v.Output)
+	}
+	return resStrings, nil
+}
+
+// CollectTaskResults собирает результаты slave-нод и возвращает подтвержденные кворумом результаты в порядке заданий;
+// problems - задания, по которым кворум не собран(ErrNoQuorum): их нет в результате, а у мастера это ошибка
+func CollectTaskResults(ctx context.Context, ch <-chan model.SlaveResult, tasks []*model.MasterTask, quorum int) (results []TaskResult, problems []error, err error) {
+	quorumResults := make(map[string]*taskTotals, len(tasks))
+
+	// -m: лимит выбранных строк общий для всех заданий в порядке их следования
+	limit := -1
+	if len(tasks) != 0 && tasks[0].Task.GP.MaxCount != nil {
+		limit = *tasks[0].Task.GP.MaxCount
+	}
+	cutoff := len(tasks) // задания начиная с cutoff не попадают в результат
+	confirmed := 0       // кол-во подряд идущих с начала заданий, достигших кворума
+	total := 0           // сумма выбранных строк по этим заданиям
+
+	// limitReached проверяет, набрали ли подтвержденные кворумом задания лимит -m,
+	// и если да - отменяет все последующие задания
+	limitReached := func() bool {
+		if limit < 0 {
+			return false
+		}
+		for total < limit && confirmed < len(tasks) {
+			rec, ok := quorumResults[tasks[confirmed].Task.TaskID]
+			if !ok {
+				return false
+			}
+			total += rec.selected
+			confirmed++
+		}
+		if total < limit {
+			return false
+		}
+		cutoff = confirmed
+		for _, t := range tasks[cutoff:] {
+			if t.CancelCTX != nil {
+				t.CancelCTX()
+			}
+		}
+		return true
+	}
 
 	// готовим мапу задач [TaskID]:*MasterTask чтобы по полученному результату быстро обновлять resMap
 	tasksMap := make(map[string]*model.MasterTask)
@@ -45,6 +126,9 @@ func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, t
 	// запуск горутины-сборщика
 	wg := sync.WaitGroup{}
 	wg.Go(func() {
+		if limitReached() { // -m 0
+			return
+		}
 		for {
 			select {
 			case <-ctx.Done():
@@ -64,9 +148,13 @@ func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, t
 				_, resExists := resMap[newRes.TaskID]
 				if !resExists {
 					newTT := &taskTotals{
-						task:  tasksMap[newRes.TaskID],
-						votes: 1,
-						data:  newRes.Output,
+						task:     tasksMap[newRes.TaskID],
+						votes:    1,
+						data:     newRes.Output,
+						selected: newRes.Selected,
+						matched:  newRes.Matched,
+						meta:     newRes.Meta,
+						carry:    newRes.Carry,
 					}
 					incremented = true
 					subMap := map[uint64]*taskTotals{newRes.HashSumm: newTT}
@@ -78,9 +166,13 @@ func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, t
 				_, hashExists := submap[newRes.HashSumm]
 				if !hashExists {
 					newTT := &taskTotals{
-						task:  tasksMap[newRes.TaskID],
-						votes: 1,
-						data:  newRes.Output,
+						task:     tasksMap[newRes.TaskID],
+						votes:    1,
+						data:     newRes.Output,
+						selected: newRes.Selected,
+						matched:  newRes.Matched,
+						meta:     newRes.Meta,
+						carry:    newRes.Carry,
 					}
 					incremented = true
 					submap[newRes.HashSumm] = newTT
@@ -95,8 +187,11 @@ func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, t
 					if hashRecord.task.CancelCTX != nil {
 						hashRecord.task.CancelCTX()
 					}
-					quorumResults[hashRecord.task.Task.TaskID] = &hashRecord.data
+					quorumResults[hashRecord.task.Task.TaskID] = hashRecord
 					delete(resMap, newRes.TaskID) // удаляем ключ из мапы результатов, так как уже достигнут кворум
+					if limitReached() {
+						return
+					}
 				}
 			default:
 				if len(quorumResults) == len(tasksMap) { // выход из горутины, если по завершении принятия результатов канал не закрылся
@@ -110,23 +205,115 @@ func CollectAggregateResults(ctx context.Context, ch <-chan model.SlaveResult, t
 	wg.Wait()
 
 	// формируем результат - в него попадут только задачи, достигшие кворума по результатам
-	var resStrings [][]string
-	for _, v := range tasks {
+	for i, v := range tasks[:cutoff] {
 		select {
 		case <-ctx.Done():
-			return nil, errors.New("CollectAggregateResults's context cancelled on the stage of forming a final result")
+			return nil, nil, errors.New("CollectAggregateResults's context cancelled on the stage of forming a final result")
 		default:
-			lines, ok := quorumResults[v.Task.TaskID]
+			rec, ok := quorumResults[v.Task.TaskID]
 			if !ok {
-				log.Printf("Quorum failed for file %q", v.Task.FileName)
+				problems = append(problems, taskProblem(v, ErrNoQuorum))
 				continue
 			}
-			if lines != nil {
-				resStrings = append(resStrings, *lines)
+			res := TaskResult{Task: v, Output: rec.data, Meta: rec.meta, Matched: rec.matched, Carry: rec.carry}
+			if i == cutoff-1 && limit >= 0 && total > limit { // последнее задание перебрало лимит -m - обрезаем
+				res.Output, res.Meta = trimToLimit(rec, rec.selected-(total-limit))
+			}
+			if res.Output != nil {
+				results = append(results, res)
 			}
 		}
 	}
 
+	// -c: файлы после лимита -m уже не читались, но, как и в GNU grep, у каждого есть строка со счетчиком.
+	// У заданий --remote имена файлов знает только slave-нода - для них строк нет
+	for _, v := range tasks[cutoff:] {
+		gp := &v.Task.GP
+		if gp.CountFound && !gp.FilesWithMatch && !gp.FilesWithoutMatch && len(v.Task.Paths) == 0 {
+			results = append(results, TaskResult{Task: v, Output: []string{gp.CountLine(gp.InputName(v.Task.FileName), 0)}})
+		}
+	}
+
 	// возврат результата
-	return resStrings, nil
+	return results, problems, nil
+}
+
+// trimToLimit оставляет в результате задания только первые n выбранных строк
+// и завершающий контекст -A после последней из них - так же, как это делает сама slave-нода.
+// Счетчик -c переписывается, только если -c не перекрыт -l/-L: у них в выводе имена файлов, а не счетчики
+func trimToLimit(rec *taskTotals, n int) ([]string, []model.LineMeta) {
+	gp := rec.task.Task.GP
+	if gp.CountFound && !gp.FilesWithMatch && !gp.FilesWithoutMatch {
+		if len(rec.data) == 0 {
+			return rec.data, rec.meta
+		}
+		count := rec.data[0]
+		return []string{strings.TrimSuffix(count, strconv.Itoa(rec.selected)) + strconv.Itoa(n)}, rec.meta
+	}
+
+	if len(rec.meta) != len(rec.data) {
+		log.Printf("Malformed line markup for file %q: result is not trimmed", rec.task.Task.FileName)
+		return rec.data, rec.meta
+	}
+
+	end := 0
+	for seen := 0; end < len(rec.meta) && seen < n; end++ {
+		if rec.meta[end].Kind == model.LineSelected {
+			seen++
+		}
+	}
+	for after := 0; end < len(rec.meta) && after < gp.CtxAfter; end++ {
+		if rec.meta[end].Kind == model.LineGroupSep {
+			break
+		}
+		after++
+	}
+	return rec.data[:end], rec.meta[:end]
+}
+
+// CollectNodeResults собирает результаты без кворума(--no-quorum): у slave-нод разные данные, поэтому
+// результат каждой ноды выводится отдельно. Сбор идет до закрытия канала или отмены ctx; результаты
+// упорядочены по заданиям, а внутри задания - по имени ноды. Лимит -m каждая нода применяет сама.
+// problems - задания, по которым не ответила ни одна нода(ErrNoResult)
+func CollectNodeResults(ctx context.Context, ch <-chan model.SlaveResult, tasks []*model.MasterTask) (results []TaskResult, problems []error, err error) {
+	byTask := make(map[string][]TaskResult, len(tasks))
+	tasksMap := make(map[string]*model.MasterTask, len(tasks))
+	for _, t := range tasks {
+		tasksMap[t.Task.TaskID] = t
+	}
+
+collect:
+	for {
+		select {
+		case <-ctx.Done():
+			break collect
+		case newRes, ok := <-ch:
+			if !ok {
+				break collect
+			}
+			task, exists := tasksMap[newRes.TaskID]
+			if !exists {
+				continue
+			}
+			byTask[newRes.TaskID] = append(byTask[newRes.TaskID], TaskResult{
+				Task:    task,
+				Node:    newRes.Node,
+				Output:  newRes.Output,
+				Meta:    newRes.Meta,
+				Matched: newRes.Matched,
+				Carry:   newRes.Carry,
+			})
+		}
+	}
+
+	for _, t := range tasks {
+		nodeResults := byTask[t.Task.TaskID]
+		if len(nodeResults) == 0 {
+			problems = append(problems, taskProblem(t, ErrNoResult))
+			continue
+		}
+		sort.SliceStable(nodeResults, func(i, j int) bool { return nodeResults[i].Node < nodeResults[j].Node })
+		results = append(results, nodeResults...)
+	}
+	return results, problems, nil
 }
@@ -67,6 +67,34 @@ func TestCollectAggregateResults(t *testing.T) {
 			wantErr:   "",
 			wantRes:   [][]string{{"1", "2", "3"}, {"1", "2", "3"}},
 		},
+		{
+			name: "Positive - max count reached, remaining tasks cut off",
+			testCtx: func() struct {
+				ctx    context.Context
+				cancel context.CancelFunc
+			} {
+				tctx, tcancel := context.WithTimeout(context.Background(), 5*time.Second)
+				return struct {
+					ctx    context.Context
+					cancel context.CancelFunc
+				}{
+					ctx: tctx, cancel: tcancel,
+				}
+			}(),
+			testCh: make(chan model.SlaveResult),
+			testTasks: []*model.MasterTask{
+				{Task: model.TaskDTO{TaskID: "task1", GP: model.GrepParam{MaxCount: intPtr(2)}}},
+				{Task: model.TaskDTO{TaskID: "task2", GP: model.GrepParam{MaxCount: intPtr(2)}}},
+				{Task: model.TaskDTO{TaskID: "task3", GP: model.GrepParam{MaxCount: intPtr(2)}}},
+			},
+			testRes: []model.SlaveResult{
+				{TaskID: "task2", HashSumm: 200, Output: []string{"2", "3"}, Selected: 2, Meta: []model.LineMeta{{Kind: model.LineSelected}, {Kind: model.LineSelected}}},
+				{TaskID: "task1", HashSumm: 100, Output: []string{"1"}, Selected: 1, Meta: []model.LineMeta{{Kind: model.LineSelected}}},
+			},
+			testQ:   1,
+			wantErr: "",
+			wantRes: [][]string{{"1"}, {"2"}},
+		},
 	}
 
 	for _, tt := range cases {
@@ -91,3 +119,139 @@ func TestCollectAggregateResults(t *testing.T) {
 		})
 	}
 }
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestCollectTaskResultsProblems(t *testing.T) {
+	tasks := []*model.MasterTask{
+		{Task: model.TaskDTO{TaskID: "task1", FileName: "a.log"}},
+		{Task: model.TaskDTO{TaskID: "task2", FileName: "b.log"}},
+		{Task: model.TaskDTO{TaskID: "task3"}},
+	}
+	ch := make(chan model.SlaveResult, 4)
+	ch <- model.SlaveResult{TaskID: "task1", HashSumm: 1, Output: []string{"a"}}
+	ch <- model.SlaveResult{TaskID: "task1", HashSumm: 1, Output: []string{"a"}}
+	ch <- model.SlaveResult{TaskID: "task2", HashSumm: 1, Output: []string{"b"}}
+	ch <- model.SlaveResult{TaskID: "task2", HashSumm: 2, Output: []string{"c"}}
+	close(ch)
+
+	res, problems, err := qaggr.CollectTaskResults(context.Background(), ch, tasks, 2)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, []string{"a"}, res[0].Output)
+
+	// файлы без кворума - ошибки мастера; у stdin имя "(standard input)"
+	require.Len(t, problems, 2)
+	require.ErrorIs(t, problems[0], qaggr.ErrNoQuorum)
+	require.Equal(t, "b.log: "+qaggr.ErrNoQuorum.Error(), problems[0].Error())
+	require.Equal(t, model.StdinName+": "+qaggr.ErrNoQuorum.Error(), problems[1].Error())
+}
+
+// TestCollectTaskResultsCountCutoff: при -c у файлов после лимита -m есть строка со счетчиком 0, как в GNU grep
+func TestCollectTaskResultsCountCutoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		gp       model.GrepParam
+		maxCount int
+		paths    bool // задания --remote: имена файлов знает только нода
+		nodeRes  []model.SlaveResult
+		wantRes  []string
+	}{
+		{
+			name:     "Positive - files after the limit are counted as 0",
+			gp:       model.GrepParam{CountFound: true, PrintFileName: true},
+			maxCount: 2,
+			nodeRes:  []model.SlaveResult{{TaskID: "task1", HashSumm: 1, Output: []string{"a.log:2"}, Selected: 2}},
+			wantRes:  []string{"a.log:2", "b.log:0", model.StdinName + ":0"},
+		},
+		{
+			name:     "Positive - -Z after file names",
+			gp:       model.GrepParam{CountFound: true, PrintFileName: true, NullName: true},
+			maxCount: 2,
+			nodeRes:  []model.SlaveResult{{TaskID: "task1", HashSumm: 1, Output: []string{"a.log\x002"}, Selected: 2}},
+			wantRes:  []string{"a.log\x002", "b.log\x000", model.StdinName + "\x000"},
+		},
+		{
+			name:     "Positive - -h, counters only",
+			gp:       model.GrepParam{CountFound: true},
+			maxCount: 1,
+			nodeRes:  []model.SlaveResult{{TaskID: "task1", HashSumm: 1, Output: []string{"3"}, Selected: 3}},
+			wantRes:  []string{"1", "0", "0"},
+		},
+		{
+			name:    "Positive - -m 0, every file is counted as 0",
+			gp:      model.GrepParam{CountFound: true, PrintFileName: true},
+			wantRes: []string{"a.log:0", "b.log:0", model.StdinName + ":0"},
+		},
+		{
+			name:     "Positive - -l overrides -c, no lines after the limit",
+			gp:       model.GrepParam{CountFound: true, FilesWithMatch: true},
+			maxCount: 1,
+			nodeRes:  []model.SlaveResult{{TaskID: "task1", HashSumm: 1, Output: []string{"a.log"}, Selected: 1}},
+			wantRes:  []string{"a.log"},
+		},
+		{
+			name:     "Positive - --remote, names after the limit are unknown",
+			gp:       model.GrepParam{CountFound: true, PrintFileName: true, Remote: true},
+			maxCount: 1,
+			paths:    true,
+			nodeRes:  []model.SlaveResult{{TaskID: "task1", HashSumm: 1, Output: []string{"a.log:1"}, Selected: 1}},
+			wantRes:  []string{"a.log:1"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			gp := tt.gp
+			gp.MaxCount = &tt.maxCount
+			var tasks []*model.MasterTask
+			for i, name := range []string{"a.log", "b.log", ""} {
+				task := model.TaskDTO{TaskID: fmt.Sprintf("task%d", i+1), GP: gp, FileName: name}
+				if tt.paths {
+					task.Paths, task.FileName = []string{name + "*"}, ""
+				}
+				tasks = append(tasks, &model.MasterTask{Task: task})
+			}
+			ch := make(chan model.SlaveResult, len(tt.nodeRes))
+			for _, v := range tt.nodeRes {
+				ch <- v
+			}
+			close(ch)
+
+			res, problems, err := qaggr.CollectTaskResults(context.Background(), ch, tasks, 1)
+			require.NoError(t, err)
+			require.Empty(t, problems)
+			var got []string
+			for _, v := range res {
+				got = append(got, v.Output...)
+			}
+			require.Equal(t, tt.wantRes, got)
+		})
+	}
+}
+
+func TestCollectNodeResults(t *testing.T) {
+	tasks := []*model.MasterTask{{Task: model.TaskDTO{TaskID: "task1"}}, {Task: model.TaskDTO{TaskID: "task2"}}, {Task: model.TaskDTO{TaskID: "task3", FileName: "c.log"}}}
+	ch := make(chan model.SlaveResult, 5)
+	ch <- model.SlaveResult{TaskID: "task2", Node: "b", Output: []string{"b2"}}
+	ch <- model.SlaveResult{TaskID: "task1", Node: "b", Output: []string{"b1"}}
+	ch <- model.SlaveResult{TaskID: "task1", Node: "a", Output: []string{"a1"}}
+	ch <- model.SlaveResult{TaskID: "unknown", Node: "a", Output: []string{"x"}}
+	ch <- model.SlaveResult{TaskID: "task2", Node: "a", Output: []string{}}
+	close(ch)
+
+	res, problems, err := qaggr.CollectNodeResults(context.Background(), ch, tasks)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.ErrorIs(t, problems[0], qaggr.ErrNoResult)
+	require.Equal(t, "c.log: "+qaggr.ErrNoResult.Error(), problems[0].Error())
+
+	// результаты упорядочены по заданиям, а внутри задания - по имени ноды; голосования нет
+	var got []string
+	for _, r := range res {
+		got = append(got, fmt.Sprintf("%s/%s:%v", r.Task.Task.TaskID, r.Node, r.Output))
+	}
+	require.Equal(t, []string{"task1/a:[a1]", "task1/b:[b1]", "task2/a:[]", "task2/b:[b2]"}, got)
+}
@@ -0,0 +1,227 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// ограничения архивов по умолчанию
+const (
+	DefaultMaxMemberSize     = 256 << 20 // размер одного файла архива после распаковки
+	DefaultMaxArchiveSize    = 1 << 30   // общий размер файлов одного архива после распаковки
+	DefaultMaxArchiveMembers = 10000     // число файлов в одном архиве
+)
+
+// errTooLarge - файл архива после распаковки оказался больше разрешенного
+var errTooLarge = errors.New("too large")
+
+// ArchiveMember - прочитанный файл из архива: путь внутри архива и его строки
+type ArchiveMember struct {
+	Path string
+	Input
+}
+
+// Name - имя файла из архива для вывода, как "архив:путь"; у файла, который не архив(пустой Path), - имя самого файла
+func (m ArchiveMember) Name(fileName string) string {
+	if m.Path == "" {
+		return fileName
+	}
+	return fileName + ":" + m.Path
+}
+
+// ReadMembers читает файл: при opts.Archives архив читается файл за файлом, а любой другой файл
+// возвращается единственным ArchiveMember с пустым Path
+func ReadMembers(fileName string, opts Options) ([]ArchiveMember, error) {
+	if opts.Archives {
+		members, ok, err := ReadArchive(fileName, opts)
+		if ok || err != nil {
+			return members, err
+		}
+	}
+	input, err := ReadInput(nil, fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return []ArchiveMember{{Input: input}}, nil
+}
+
+// ReadArchive читает tar(в том числе .tar.gz и .tar.bz2) или zip архив файл за файлом; ok сообщает, архив ли
+// это вообще - если нет, файл нужно читать через ReadInput. Каталоги, ссылки и прочие специальные записи
+// пропускаются, как и файлы больше opts.MaxMemberSize(по заголовку или по фактическому размеру после
+// распаковки); вложенные архивы не раскрываются. Сжатые файлы внутри архива распаковываются только при
+// opts.Decompress. Архив, в котором больше opts.MaxArchiveMembers файлов или файлы которого вместе больше
+// opts.MaxArchiveSize байт, не читается вовсе - возвращается ошибка
+func ReadArchive(fileName string, opts Options) (members []ArchiveMember, ok bool, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't open file %q: %w", fileName, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't stat file %q: %v", fileName, err)
+	}
+	if info.IsDir() {
+		return nil, false, fmt.Errorf("specified source filename %q is a directory: %w", fileName, errIsDirectory)
+	}
+
+	br := bufio.NewReader(file)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("couldn't read file %q: %v", fileName, err)
+	}
+	if bytes.Equal(magic, []byte("PK\x03\x04")) || bytes.Equal(magic, []byte("PK\x05\x06")) {
+		members, err := readZip(file, info.Size(), fileName, opts)
+		return members, true, err
+	}
+
+	// tar узнается по сигнатуре "ustar" в заголовке первой записи - в том числе под gzip и bzip2
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, false, nil // не gzip - пусть ReadInput разбирается с файлом сам
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		r = bzip2.NewReader(br)
+	}
+	tr := bufio.NewReader(r)
+	header, err := tr.Peek(262)
+	if err != nil || !bytes.Equal(header[257:262], []byte("ustar")) {
+		return nil, false, nil
+	}
+	members, err = readTar(tr, fileName, opts)
+	return members, true, err
+}
+
+func readTar(r io.Reader, fileName string, opts Options) ([]ArchiveMember, error) {
+	ar := archiveReader{fileName: fileName, opts: opts}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return ar.members, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read archive %q: %v", fileName, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := ar.read(tr, hdr.Name, hdr.Size); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readZip(r io.ReaderAt, size int64, fileName string, opts Options) ([]ArchiveMember, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read archive %q: %v", fileName, err)
+	}
+
+	ar := archiveReader{fileName: fileName, opts: opts}
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		rc, err := zf.Open() // распаковка начнется только при чтении, после проверки размера из заголовка
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %q in archive %q: %v", zf.Name, fileName, err)
+		}
+		err = ar.read(rc, zf.Name, int64(zf.UncompressedSize64))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ar.members, nil
+}
+
+// archiveReader собирает файлы одного архива и следит за ограничениями: размером каждого файла, числом
+// файлов и их общим размером после распаковки
+type archiveReader struct {
+	fileName string
+	opts     Options
+	members  []ArchiveMember
+	total    int64 // байт в прочитанных файлах
+}
+
+// read читает файл архива path размером size по заголовку. Файл больше --max-member-size пропускается
+// с предупреждением, а превышение --max-archive-members или --max-archive-size - ошибка всего архива
+func (ar *archiveReader) read(r io.Reader, path string, size int64) error {
+	maxMember := maxMemberSize(ar.opts)
+	if size > maxMember {
+		log.Printf("warning: %q: skipping %q: %d bytes is more than --max-member-size", ar.fileName, path, size)
+		return nil
+	}
+	if maxMembers := limitOrDefault(int64(ar.opts.MaxArchiveMembers), DefaultMaxArchiveMembers); int64(len(ar.members)) >= maxMembers {
+		return fmt.Errorf("archive %q has more than %d files: raise --max-archive-members", ar.fileName, maxMembers)
+	}
+	maxArchive := limitOrDefault(ar.opts.MaxArchiveSize, DefaultMaxArchiveSize)
+
+	opts := ar.opts
+	opts.sizeLimit = min(maxMember, maxArchive-ar.total)
+	input, err := readStream(r, ar.fileName+":"+path, opts)
+	switch {
+	case errors.Is(err, errTooLarge) && opts.sizeLimit < maxMember:
+		return fmt.Errorf("files of archive %q are larger than %d bytes: raise --max-archive-size", ar.fileName, maxArchive)
+	case errors.Is(err, errTooLarge):
+		log.Printf("warning: %q: skipping %q: more than --max-member-size(%d bytes) after decompression", ar.fileName, path, maxMember)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	for _, line := range input.Lines {
+		ar.total += int64(len(line)) + 1
+	}
+	ar.members = append(ar.members, ArchiveMember{Path: path, Input: input})
+	return nil
+}
+
+// sizeLimitReader отдает не больше left байт, а на попытку прочитать больше возвращает errTooLarge
+type sizeLimitReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (lr *sizeLimitReader) Read(p []byte) (int, error) {
+	if lr.left <= 0 {
+		// данных ровно на лимит - не ошибка
+		if n, err := lr.r.Read(make([]byte, 1)); n == 0 {
+			return 0, err
+		}
+		return 0, errTooLarge
+	}
+	if int64(len(p)) > lr.left {
+		p = p[:lr.left]
+	}
+	n, err := lr.r.Read(p)
+	lr.left -= int64(n)
+	return n, err
+}
+
+func maxMemberSize(opts Options) int64 {
+	return limitOrDefault(opts.MaxMemberSize, DefaultMaxMemberSize)
+}
+
+func limitOrDefault(limit, def int64) int64 {
+	if limit <= 0 {
+		return def
+	}
+	return limit
+}
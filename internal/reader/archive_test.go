@@ -0,0 +1,199 @@
+package reader_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/stretchr/testify/require"
+)
+
+type archiveFile struct {
+	name    string
+	content string
+}
+
+func TestReadMembers(t *testing.T) {
+	files := []archiveFile{
+		{name: "logs/a.log", content: "a1\na2\n"},
+		{name: "big.log", content: "0123456789\n"},
+		{name: "b.log", content: "b1"},
+	}
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		return path
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		opts     reader.Options
+		wantRes  map[string][]string
+		wantErr  string
+		wantKeys []string
+	}{
+		{
+			name:     "Positive - tar",
+			path:     writeFile("bundle.tar", makeTar(t, files)),
+			opts:     reader.Options{Archives: true, MaxMemberSize: 8},
+			wantKeys: []string{"bundle.tar:logs/a.log", "bundle.tar:b.log"},
+			wantRes:  map[string][]string{"bundle.tar:logs/a.log": {"a1", "a2"}, "bundle.tar:b.log": {"b1"}},
+		},
+		{
+			name:     "Positive - tar.gz",
+			path:     writeFile("bundle.tar.gz", gzipData(t, makeTar(t, files))),
+			opts:     reader.Options{Archives: true},
+			wantKeys: []string{"bundle.tar.gz:logs/a.log", "bundle.tar.gz:big.log", "bundle.tar.gz:b.log"},
+			wantRes: map[string][]string{
+				"bundle.tar.gz:logs/a.log": {"a1", "a2"},
+				"bundle.tar.gz:big.log":    {"0123456789"},
+				"bundle.tar.gz:b.log":      {"b1"},
+			},
+		},
+		{
+			name:     "Positive - zip",
+			path:     writeFile("bundle.zip", makeZip(t, files)),
+			opts:     reader.Options{Archives: true, MaxMemberSize: 8},
+			wantKeys: []string{"bundle.zip:logs/a.log", "bundle.zip:b.log"},
+			wantRes:  map[string][]string{"bundle.zip:logs/a.log": {"a1", "a2"}, "bundle.zip:b.log": {"b1"}},
+		},
+		{
+			name:     "Positive - archive read as a file without --archives",
+			path:     writeFile("plain.zip", makeZip(t, files[:1])),
+			opts:     reader.Options{},
+			wantKeys: []string{"plain.zip"},
+		},
+		{
+			name:     "Positive - not an archive",
+			path:     writeFile("text.gz", gzipData(t, []byte("t1\n"))),
+			opts:     reader.Options{Archives: true, Decompress: true},
+			wantKeys: []string{"text.gz"},
+			wantRes:  map[string][]string{"text.gz": {"t1"}},
+		},
+		{
+			name:     "Positive - gzip inside tar larger than the limit after decompression",
+			path:     writeFile("nested.tar", makeTar(t, []archiveFile{{name: "bomb.gz", content: string(gzipData(t, []byte(strings.Repeat("x\n", 1000))))}, files[2]})),
+			opts:     reader.Options{Archives: true, Decompress: true, MaxMemberSize: 100},
+			wantKeys: []string{"nested.tar:b.log"},
+		},
+		{
+			name:     "Positive - files exactly at the archive size limit",
+			path:     writeFile("exact.zip", makeZip(t, files)),
+			opts:     reader.Options{Archives: true, MaxArchiveSize: 6 + 11 + 2},
+			wantKeys: []string{"exact.zip:logs/a.log", "exact.zip:big.log", "exact.zip:b.log"},
+		},
+		{
+			name:    "Negative - too many files in archive",
+			path:    writeFile("many.tar", makeTar(t, files)),
+			opts:    reader.Options{Archives: true, MaxArchiveMembers: 2},
+			wantErr: "more than 2 files",
+		},
+		{
+			name:    "Negative - files of archive larger than the archive limit",
+			path:    writeFile("large.zip", makeZip(t, files)),
+			opts:    reader.Options{Archives: true, MaxArchiveSize: 10},
+			wantErr: "larger than 10 bytes",
+		},
+		{
+			name:    "Negative - broken zip",
+			path:    writeFile("broken.zip", []byte("PK\x03\x04broken")),
+			opts:    reader.Options{Archives: true},
+			wantErr: "couldn't read archive",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := reader.ReadMembers(tt.path, tt.opts)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var keys []string
+			for _, m := range members {
+				name := m.Name(filepath.Base(tt.path))
+				keys = append(keys, name)
+				if want, ok := tt.wantRes[name]; ok {
+					require.Equal(t, want, m.Lines)
+				}
+			}
+			require.Equal(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func makeTar(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(f.content))}))
+		_, err := tw.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "link.log", Typeflag: tar.TypeSymlink, Linkname: "b.log"}))
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// TestReadZipLyingHeader: файл zip больше размера из своего заголовка не обрезается молча
+func TestReadZipLyingHeader(t *testing.T) {
+	content := strings.Repeat("a\n", 100)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "a.log",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(content)),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: 10,
+	})
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	path := filepath.Join(t.TempDir(), "lying.zip")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	_, err = reader.ReadMembers(path, reader.Options{Archives: true, MaxMemberSize: 50})
+	require.ErrorContains(t, err, `lying.zip:a.log": zip: not a valid zip file`)
+}
+
+func makeZip(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err := zw.Create("logs/")
+	require.NoError(t, err)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,74 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// EncodingAuto - кодировка входа по умолчанию: по BOM распознаются UTF-8 и UTF-16LE/BE, а вход без BOM
+// читается как есть(как UTF-8)
+const EncodingAuto = "auto"
+
+// LookupEncoding ищет кодировку по имени IANA или его псевдониму("utf-16le", "latin1", "windows-1251" и т.п.);
+// для EncodingAuto и пустого имени возвращает nil
+func LookupEncoding(name string) (encoding.Encoding, error) {
+	if name == "" || strings.EqualFold(name, EncodingAuto) {
+		return nil, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return enc, nil
+}
+
+// errOffsetsTranscoded - -b на перекодированном входе: смещения в UTF-8 не совпали бы со смещениями в файле
+var errOffsetsTranscoded = errors.New("byte offsets(-b) are not supported for input converted to UTF-8(--encoding or UTF-16 BOM)")
+
+// transcode перекодирует вход в UTF-8. BOM в начале входа всегда важнее имени кодировки и в строки не попадает.
+// Невалидные последовательности заданной или найденной по BOM кодировки заменяются на U+FFFD; вход
+// без BOM при EncodingAuto не меняется - невалидный UTF-8 в нем по-прежнему делает вход двоичным, как и
+// после отброшенного BOM UTF-8. skipped - длина отброшенного BOM UTF-8: остальные байты входа не меняются,
+// и смещения строк в файле на нее больше. Перекодированный вход при offsets(-b) - ошибка errOffsetsTranscoded
+func transcode(r io.Reader, name string, offsets bool) (res io.Reader, skipped int64, err error) {
+	enc, err := LookupEncoding(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if enc != nil {
+		if offsets {
+			return nil, 0, errOffsetsTranscoded
+		}
+		return transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder())), 0, nil
+	}
+
+	// без BOM вход не трогаем, чтобы не гонять каждый байт через transform
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, utf8BOM):
+		n, err := br.Discard(len(utf8BOM))
+		return br, int64(n), err
+	case bytes.HasPrefix(magic, []byte("\xff\xfe")) || bytes.HasPrefix(magic, []byte("\xfe\xff")):
+		if offsets {
+			return nil, 0, errOffsetsTranscoded
+		}
+		return transform.NewReader(br, unicode.BOMOverride(transform.Nop)), 0, nil
+	}
+	return br, 0, nil
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
@@ -0,0 +1,143 @@
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// Follower следит за файлом в режиме --follow, как tail -F: при каждом опросе отдает строки, дописанные
+// с прошлого опроса. Незавершенная последняя строка ждет своего разделителя. Если файл усечен, он
+// перечитывается с начала; если по пути появился другой файл(ротация), старый дочитывается до конца и
+// дальше читается новый; пока файла нет, опрос просто ничего не возвращает.
+type Follower struct {
+	name   string
+	opts   Options
+	file   *os.File
+	offset int64 // смещение начала первой непрочитанной строки
+	line   int   // кол-во уже прочитанных строк
+	absent bool  // о том, что файла нет, уже сообщили
+}
+
+// Chunk - строки, дописанные в файл: BaseOffset и BaseLine - смещение и кол-во строк файла перед ними,
+// смещения Offsets считаются от BaseOffset
+type Chunk struct {
+	Input
+	BaseOffset int64
+	BaseLine   int
+}
+
+// NewFollower создает Follower; файл открывается при первом опросе. Сжатый вход в этом режиме не распаковывается,
+// а перекодирование не выполняется - смещения в файле должны совпадать со смещениями строк
+func NewFollower(fileName string, opts Options) *Follower {
+	opts.Decompress = false
+	opts.Encoding = ""
+	return &Follower{
+		name: fileName,
+		opts: opts,
+	}
+}
+
+// Name - путь файла, за которым следит Follower
+func (f *Follower) Name() string {
+	return f.name
+}
+
+// Poll возвращает новые строки файла; пустой Chunk - новых строк нет
+func (f *Follower) Poll() (Chunk, error) {
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				if !f.absent {
+					log.Printf("warning: %q: file doesn't exist, waiting for it to appear", f.name)
+					f.absent = true
+				}
+				return Chunk{}, nil
+			}
+			return Chunk{}, err
+		}
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return Chunk{}, fmt.Errorf("couldn't stat file %q: %v", f.name, err)
+	}
+	if info.Size() < f.offset {
+		log.Printf("warning: %q: file truncated, reading from the beginning", f.name)
+		f.offset, f.line = 0, 0
+	}
+
+	// ротация: по пути теперь другой файл или его нет - старый дочитываем вместе с незавершенной строкой
+	pathInfo, err := os.Stat(f.name)
+	rotated := err != nil || !os.SameFile(info, pathInfo)
+
+	chunk, err := f.read(!rotated)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	if rotated {
+		if pathInfo != nil {
+			log.Printf("warning: %q: file has been replaced, following the new file", f.name)
+		}
+		f.file.Close()
+		f.file = nil
+		f.offset, f.line = 0, 0
+	}
+	return chunk, nil
+}
+
+// Close закрывает отслеживаемый файл
+func (f *Follower) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *Follower) open() error {
+	file, err := os.Open(f.name)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("couldn't stat file %q: %v", f.name, err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return fmt.Errorf("specified source filename %q is a directory", f.name)
+	}
+	if f.absent {
+		log.Printf("warning: %q: file has appeared, following it", f.name)
+		f.absent = false
+	}
+	f.file = file
+	return nil
+}
+
+func (f *Follower) read(keepPartial bool) (Chunk, error) {
+	if _, err := f.file.Seek(f.offset, io.SeekStart); err != nil {
+		return Chunk{}, fmt.Errorf("couldn't read file %q: %v", f.name, err)
+	}
+	input, consumed, err := readLines(bufio.NewReaderSize(f.file, readBufSize), f.name, f.line, f.opts, keepPartial)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	chunk := Chunk{
+		Input:      input,
+		BaseOffset: f.offset,
+		BaseLine:   f.line,
+	}
+	f.offset += consumed
+	f.line += len(input.Lines)
+	return chunk, nil
+}
@@ -0,0 +1,70 @@
+package reader_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowerPoll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := reader.NewFollower(path, reader.Options{Offsets: true})
+	defer f.Close()
+
+	appendFile := func(content string) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = file.WriteString(content)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+	poll := func() reader.Chunk {
+		t.Helper()
+		chunk, err := f.Poll()
+		require.NoError(t, err)
+		return chunk
+	}
+
+	// файла еще нет
+	require.Empty(t, poll().Lines)
+
+	// первые строки и незавершенная строка
+	appendFile("line1\nline2\npart")
+	chunk := poll()
+	require.Equal(t, []string{"line1", "line2"}, chunk.Lines)
+	require.Equal(t, []int64{0, 6}, chunk.Offsets)
+	require.Equal(t, 0, chunk.BaseLine)
+
+	// строка дописана
+	appendFile("ial\nline4\n")
+	chunk = poll()
+	require.Equal(t, []string{"partial", "line4"}, chunk.Lines)
+	require.Equal(t, int64(12), chunk.BaseOffset)
+	require.Equal(t, []int64{0, 8}, chunk.Offsets)
+	require.Equal(t, 2, chunk.BaseLine)
+	require.Empty(t, poll().Lines)
+
+	// усечение - файл читается с начала
+	require.NoError(t, os.Truncate(path, 0))
+	require.Empty(t, poll().Lines)
+	appendFile("new1\n")
+	chunk = poll()
+	require.Equal(t, []string{"new1"}, chunk.Lines)
+	require.Equal(t, int64(0), chunk.BaseOffset)
+	require.Equal(t, 0, chunk.BaseLine)
+
+	// ротация - старый файл дочитывается вместе с незавершенной строкой, затем читается новый
+	appendFile("tail")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile("rotated1\n")
+	chunk = poll()
+	require.Equal(t, []string{"tail"}, chunk.Lines)
+	require.Equal(t, 1, chunk.BaseLine)
+	chunk = poll()
+	require.Equal(t, []string{"rotated1"}, chunk.Lines)
+	require.Equal(t, 0, chunk.BaseLine)
+}
@@ -0,0 +1,92 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Glob работает как filepath.Glob, но дополнительно понимает сегмент "**" - любое кол-во вложенных каталогов,
+// в том числе ни одного("logs/**/*.log" находит и "logs/a.log", и "logs/2024/01/a.log"). При обходе "**"
+// символические ссылки на каталоги не разыменовываются - это защищает от циклов. Совпадения выдаются в
+// порядке обхода каталогов, без повторов
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	g := globber{seen: make(map[string]struct{})}
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	dir := ""
+	if parts[0] == "" { // абсолютный путь
+		dir, parts = string(os.PathSeparator), parts[1:]
+	}
+	g.match(dir, parts)
+	return g.matches, nil
+}
+
+type globber struct {
+	seen    map[string]struct{}
+	matches []string
+}
+
+func (g *globber) match(dir string, parts []string) {
+	if len(parts) == 0 {
+		if _, ok := g.seen[dir]; !ok && dir != "" {
+			g.seen[dir] = struct{}{}
+			g.matches = append(g.matches, dir)
+		}
+		return
+	}
+
+	part, rest := parts[0], parts[1:]
+	switch {
+	case part == "**":
+		g.match(dir, rest) // ни одного каталога
+		for _, e := range readDirQuiet(dir) {
+			if e.IsDir() {
+				g.match(globJoin(dir, e.Name()), parts)
+			}
+		}
+	case part == "":
+		g.match(dir, rest) // "a//b" - лишний разделитель
+	case !hasGlobMeta(part):
+		path := globJoin(dir, part)
+		if _, err := os.Lstat(path); err == nil {
+			g.match(path, rest)
+		}
+	default:
+		for _, e := range readDirQuiet(dir) {
+			if ok, _ := filepath.Match(part, e.Name()); ok {
+				g.match(globJoin(dir, e.Name()), rest)
+			}
+		}
+	}
+}
+
+// readDirQuiet читает каталог; каталоги, которые не удалось прочитать, как и в filepath.Glob, просто не дают совпадений
+func readDirQuiet(dir string) []os.DirEntry {
+	if dir == "" {
+		dir = "."
+	}
+	entries, _ := os.ReadDir(dir)
+	return entries
+}
+
+func globJoin(dir, name string) string {
+	switch {
+	case dir == "":
+		return name
+	case strings.HasSuffix(dir, string(os.PathSeparator)):
+		return dir + name
+	default:
+		return dir + string(os.PathSeparator) + name
+	}
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
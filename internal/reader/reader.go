@@ -1,61 +1,289 @@
 // Package reader provides means of reading input specified at launch:
 // - stdIn if no filenames is specified,
-// - local file.
+// - local file;
+// gzip, bzip2 and zlib input can be decompressed on the fly, tar and zip archives are read member by member;
+// input in UTF-16 or a legacy encoding is converted to UTF-8.
 package reader
 
 import (
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
-func ReadInput(stdIn io.Reader, fileName string) ([]string, error) {
+// Options - параметры чтения входа
+type Options struct {
+	NullData   bool   // -z — записи разделяются NUL-байтом, а не переводом строки
+	Offsets    bool   // -b — запоминать байтовое смещение начала каждой строки
+	Decompress bool   // --decompress — распаковывать сжатый вход(gzip, bzip2, zlib) на лету
+	Encoding   string // --encoding — кодировка входа(см. LookupEncoding); пусто — EncodingAuto
+
+	MaxLineSize       int  // --max-line-size — максимальная длина строки в байтах; 0 — DefaultMaxLineSize
+	TruncateLongLines bool // --long-lines=truncate — обрезать длинные строки с пометкой TruncatedMarker вместо ошибки
+
+	Archives          bool  // --archives — ReadMembers читает tar и zip архивы файл за файлом
+	MaxMemberSize     int64 // --max-member-size — максимальный размер файла внутри архива; 0 — DefaultMaxMemberSize
+	MaxArchiveSize    int64 // --max-archive-size — максимальный общий размер файлов архива; 0 — DefaultMaxArchiveSize
+	MaxArchiveMembers int   // --max-archive-members — максимальное число файлов в архиве; 0 — DefaultMaxArchiveMembers
+
+	sizeLimit int64 // файл архива: сколько байт можно прочитать после распаковки; 0 — без ограничения
+}
+
+const (
+	// DefaultMaxLineSize - максимальная длина строки по умолчанию
+	DefaultMaxLineSize = 64 << 20
+	// TruncatedMarker дописывается к строке, обрезанной по --max-line-size
+	TruncatedMarker = " [line truncated]"
+)
+
+// Input - прочитанный вход: строки без разделителей и, если запрошено, смещения их начала в байтах
+type Input struct {
+	Lines   []string
+	Offsets []int64 // заполняется только при Options.Offsets; len(Offsets) == len(Lines)
+}
+
+func ReadInput(stdIn io.Reader, fileName string, opts Options) (Input, error) {
 	switch fileName {
 	case "":
-		return readStdIn(stdIn)
+		return readStdIn(stdIn, opts)
 	default:
-		return readFile(fileName)
+		return readFile(fileName, opts)
 	}
 }
 
-func readStdIn(stdIn io.Reader) ([]string, error) {
-	result := make([]string, 0)
-	scanner := bufio.NewScanner(stdIn)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || line == "\x1A" {
-			continue // пропускаем пустые строки
+// IsBinary распознает двоичный вход так же, как GNU grep в UTF-8 локали:
+// вход двоичный, если в нем есть NUL-байт или невалидная UTF-8 последовательность
+func IsBinary(lines []string) bool {
+	for _, line := range lines {
+		if strings.IndexByte(line, 0) >= 0 || !utf8.ValidString(line) {
+			return true
 		}
-		result = append(result, line)
 	}
-	return result, scanner.Err()
+	return false
+}
+
+func readStdIn(stdIn io.Reader, opts Options) (Input, error) {
+	result, err := readStream(stdIn, "", opts)
+	if err != nil || !isTerminal(stdIn) {
+		return result, err
+	}
+
+	// Ctrl+Z в конце ввода с консоли Windows - признак конца ввода, а не строка; в файлах и
+	// перенаправленном stdin это обычные данные
+	if n := len(result.Lines); n != 0 && result.Lines[n-1] == "\x1A" {
+		result.Lines = result.Lines[:n-1]
+		if opts.Offsets {
+			result.Offsets = result.Offsets[:n-1]
+		}
+	}
+	return result, nil
+}
+
+// isTerminal сообщает, что stdin - консоль, а не файл или канал
+func isTerminal(stdIn io.Reader) bool {
+	f, ok := stdIn.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func readFile(fileName string) ([]string, error) {
+func readFile(fileName string, opts Options) (Input, error) {
 	// проверяем открывается ли файл
 	info, err := os.Stat(fileName)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %q: %v", fileName, err)
+		return Input{}, fmt.Errorf("error opening file %q: %w", fileName, err)
 	}
 	// проверяем не папка ли это
 	if info.IsDir() {
-		return nil, fmt.Errorf("specified source filename %q is a directory", fileName)
+		return Input{}, fmt.Errorf("specified source filename %q is a directory: %w", fileName, errIsDirectory)
 	}
 
 	// открываем файл для чтения
 	file, err := os.Open(fileName)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't open file %q: %v", fileName, err)
+		return Input{}, fmt.Errorf("couldn't open file %q: %w", fileName, err)
 	}
 	defer file.Close()
 
-	// читаем и возвращаем результат
-	result := make([]string, 0)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		result = append(result, scanner.Text())
+	return readStream(file, fileName, opts)
+}
+
+// readStream - общий для stdin и файлов путь чтения. Строки сохраняются байт в байт, как в GNU grep:
+// пробелы и пустые строки не отбрасываются, а '\r' из "\r\n" остается частью строки;
+// последняя строка без завершающего перевода строки читается так же, как и остальные
+func readStream(r io.Reader, fileName string, opts Options) (Input, error) {
+	if opts.Decompress {
+		stream, err := decompress(r, fileName)
+		if err != nil {
+			return Input{}, fmt.Errorf("couldn't decompress %s: %v", inputName(fileName), err)
+		}
+		defer stream.Close()
+		r = stream
+	}
+	if opts.sizeLimit > 0 { // размер в заголовке архива ничего не гарантирует, а вложенный gzip может оказаться "бомбой"
+		r = &sizeLimitReader{r: r, left: opts.sizeLimit}
+	}
+	r, skipped, err := transcode(r, opts.Encoding, opts.Offsets)
+	if err != nil {
+		return Input{}, fmt.Errorf("couldn't read %s: %v", inputName(fileName), err)
+	}
+
+	result, _, err := readLines(bufio.NewReaderSize(r, readBufSize), fileName, 0, opts, false)
+	// -b: смещения считаются в файле - первая строка начинается с BOM, остальные сдвинуты на его длину
+	for i := 1; i < len(result.Offsets); i++ {
+		result.Offsets[i] += skipped
+	}
+	return result, err
+}
+
+// readLines читает строки до конца входа; смещения Offsets считаются от текущей позиции br.
+// При keepPartial последняя строка без завершающего разделителя не читается - она, возможно, еще
+// дописывается(--follow); consumed - сколько байт входа заняли прочитанные строки.
+// firstLine - номер первой строки во входе, нужен только для сообщений об ошибках
+func readLines(br *bufio.Reader, fileName string, firstLine int, opts Options, keepPartial bool) (result Input, consumed int64, err error) {
+	maxLine := opts.MaxLineSize
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineSize
+	}
+	sep := byte('\n')
+	if opts.NullData {
+		sep = 0
+	}
+
+	result.Lines = make([]string, 0)
+	for {
+		line, n, truncated, err := readRecord(br, sep, maxLine)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return result, consumed, err
+		}
+		if err == io.EOF && keepPartial {
+			break
+		}
+
+		if truncated {
+			if !opts.TruncateLongLines {
+				return Input{}, consumed, fmt.Errorf("line %d of %s is longer than %d bytes: raise --max-line-size or use --long-lines=truncate",
+					firstLine+len(result.Lines)+1, inputName(fileName), maxLine)
+			}
+			line = truncateLine(line, maxLine)
+		}
+		result.Lines = append(result.Lines, string(line))
+		if opts.Offsets {
+			result.Offsets = append(result.Offsets, consumed)
+		}
+		consumed += n
+
+		if err == io.EOF { // последняя строка без завершающего разделителя
+			break
+		}
+	}
+	return result, consumed, nil
+}
+
+// truncateLine обрезает строку так, чтобы вместе с TruncatedMarker она не превышала maxLine байт: --max-line-size
+// мастера по умолчанию совпадает с ограничением строки задания у slave-ноды, и более длинную строку нода отвергла бы.
+// При maxLine короче самой пометки от нее остается начало
+func truncateLine(line []byte, maxLine int) []byte {
+	keep := min(max(maxLine-len(TruncatedMarker), 0), len(line))
+	line = append(cutToRune(line[:keep]), TruncatedMarker...)
+	return line[:min(len(line), maxLine)]
+}
+
+// cutToRune отбрасывает неполный последний символ UTF-8 обрезанной строки - иначе строка стала бы
+// невалидным UTF-8 и IsBinary принял бы за двоичный весь вход
+func cutToRune(line []byte) []byte {
+	for i := len(line) - 1; i >= 0 && i >= len(line)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(line[i]) {
+			if !utf8.FullRune(line[i:]) {
+				return line[:i]
+			}
+			break
+		}
+	}
+	return line
+}
+
+// размер буфера чтения: строки длиннее него собираются из нескольких кусков
+const readBufSize = 64 << 10
+
+// readRecord читает одну запись до разделителя sep любой длины. В запись попадает не больше maxLine байт -
+// остаток строки пропускается, а truncated сообщает об этом; n - сколько байт входа поглотила запись
+// вместе с разделителем. Как и в GNU grep, '\r' из "\r\n" остается частью строки
+func readRecord(br *bufio.Reader, sep byte, maxLine int) (line []byte, n int64, truncated bool, err error) {
+	for {
+		chunk, err := br.ReadSlice(sep)
+		n += int64(len(chunk))
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+
+		if !truncated {
+			if room := maxLine - len(line); len(chunk) > room {
+				line = append(line, chunk[:room]...)
+				truncated = true
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+
+		if err != bufio.ErrBufferFull {
+			return line, n, truncated, err
+		}
+	}
+}
+
+// inputName - имя входа для сообщений об ошибках
+func inputName(fileName string) string {
+	if fileName == "" {
+		return "standard input"
+	}
+	return fmt.Sprintf("file %q", fileName)
+}
+
+// decompress распознает сжатый вход по сигнатуре, а zlib(у которого нет надежной сигнатуры) - еще и по
+// расширению имени файла; несжатый вход возвращается как есть
+func decompress(r io.Reader, name string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case isZlib(magic, name):
+		return zlib.NewReader(br)
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// isZlib проверяет заголовок zlib: метод deflate(0x78) и контрольную сумму заголовка. Под такой заголовок
+// попадает и обычный текст(например, "x^"), поэтому без расширения .zz/.zlib принимаются только
+// уровни сжатия, которые ставят распространенные упаковщики
+func isZlib(magic []byte, name string) bool {
+	if len(magic) < 2 || magic[0] != 0x78 || (uint16(magic[0])<<8|uint16(magic[1]))%31 != 0 {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".zz", ".zlib":
+		return true
 	}
-	return result, scanner.Err()
+	return magic[1] == 0x01 || magic[1] == 0x9c || magic[1] == 0xda
 }
@@ -2,9 +2,13 @@ package reader_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
@@ -53,7 +57,7 @@ func TestReadInput(t *testing.T) {
 			isDir:   false,
 			isReal:  false,
 			input:   "",
-			wantErr: "system cannot find the file specified",
+			wantErr: "error opening file",
 		},
 	}
 	for _, tt := range cases {
@@ -66,14 +70,329 @@ func TestReadInput(t *testing.T) {
 				fileName = createTempFile(t, tt.input, tt.isDir)
 			}
 
-			res, err := reader.ReadInput(tt.stdin, fileName)
+			res, err := reader.ReadInput(tt.stdin, fileName, reader.Options{})
 
 			switch tt.wantErr {
 			case "":
-				require.Equal(t, wantOutput, res, "Output array is not equal to wantOutput")
+				require.Equal(t, wantOutput, res.Lines, "Output array is not equal to wantOutput")
 			default:
 				require.ErrorContains(t, err, tt.wantErr, fmt.Sprintf("Received error %v doesn't contain %q", err, tt.wantErr))
 			}
+			if !tt.isReal { // текст ошибки "файл не найден" зависит от ОС
+				require.ErrorIs(t, err, fs.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestReadInputNullData(t *testing.T) {
+	fileName := createTempFile(t, "rec1\nline2\x00rec2\x00rec3", false)
+
+	res, err := reader.ReadInput(nil, fileName, reader.Options{NullData: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"rec1\nline2", "rec2", "rec3"}, res.Lines)
+}
+
+func TestReadInputOffsets(t *testing.T) {
+	fileName := createTempFile(t, "line1\r\nline2\n\nline4", false)
+
+	res, err := reader.ReadInput(nil, fileName, reader.Options{Offsets: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"line1\r", "line2", "", "line4"}, res.Lines)
+	require.Equal(t, []int64{0, 7, 13, 14}, res.Offsets)
+}
+
+func TestReadInputStdinSameAsFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		wantRes []string
+	}{
+		{name: "Positive - empty input", input: "", wantRes: []string{}},
+		{name: "Positive - blank lines and whitespace are kept", input: "  line1 \n\n\tline3\n", wantRes: []string{"  line1 ", "", "\tline3"}},
+		{name: "Positive - CRLF stays part of the line", input: "line1\r\nline2\r\n", wantRes: []string{"line1\r", "line2\r"}},
+		{name: "Positive - no trailing newline", input: "line1\nline2", wantRes: []string{"line1", "line2"}},
+		{name: "Positive - only newline", input: "\n", wantRes: []string{""}},
+		{name: "Positive - Ctrl+Z at the end of redirected input is data", input: "line1\n\x1A", wantRes: []string{"line1", "\x1A"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := reader.Options{Offsets: true}
+			fromStdin, err := reader.ReadInput(strings.NewReader(tt.input), "", opts)
+			require.NoError(t, err)
+			fromFile, err := reader.ReadInput(nil, createTempFile(t, tt.input, false), opts)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantRes, fromStdin.Lines)
+			require.Equal(t, fromFile, fromStdin)
+		})
+	}
+}
+
+func TestReadInputDecompress(t *testing.T) {
+	plain := "line1\nline2\n"
+	compress := func(newWriter func(w io.Writer) io.WriteCloser) string {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		_, err := w.Write([]byte(plain))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.String()
+	}
+
+	cases := []struct {
+		name    string
+		input   string
+		stdin   bool
+		wantRes []string
+		wantErr string
+	}{
+		{
+			name:    "Positive - gzip file",
+			input:   compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+			wantRes: []string{"line1", "line2"},
+		},
+		{
+			name:    "Positive - zlib stdin",
+			input:   compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+			stdin:   true,
+			wantRes: []string{"line1", "line2"},
+		},
+		{
+			name:    "Positive - plain text is read as is",
+			input:   "x^ line1\nline2",
+			wantRes: []string{"x^ line1", "line2"},
+		},
+		{
+			name:    "Negative - broken gzip header",
+			input:   "\x1f\x8bbroken",
+			wantErr: "couldn't decompress file",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var res reader.Input
+			var err error
+			switch tt.stdin {
+			case true:
+				res, err = reader.ReadInput(strings.NewReader(tt.input), "", reader.Options{Decompress: true})
+			default:
+				res, err = reader.ReadInput(nil, createTempFile(t, tt.input, false), reader.Options{Decompress: true})
+			}
+
+			switch tt.wantErr {
+			case "":
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRes, res.Lines)
+			default:
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadInputLongLines(t *testing.T) {
+	long := strings.Repeat("a", 200<<10)
+	input := long + "\nshort\n"
+
+	cases := []struct {
+		name    string
+		opts    reader.Options
+		wantRes []string
+		wantErr string
+	}{
+		{
+			name:    "Positive - line longer than 64 KiB within default limit",
+			opts:    reader.Options{Offsets: true},
+			wantRes: []string{long, "short"},
+		},
+		{
+			name:    "Positive - line truncated with marker",
+			opts:    reader.Options{Offsets: true, MaxLineSize: 10 + len(reader.TruncatedMarker), TruncateLongLines: true},
+			wantRes: []string{"aaaaaaaaaa" + reader.TruncatedMarker, "short"},
+		},
+		{
+			name:    "Positive - limit shorter than the marker",
+			opts:    reader.Options{Offsets: true, MaxLineSize: 10, TruncateLongLines: true},
+			wantRes: []string{reader.TruncatedMarker[:10], "short"},
+		},
+		{
+			name:    "Negative - line too long",
+			opts:    reader.Options{MaxLineSize: 100 << 10},
+			wantErr: "line 1 of standard input is longer than 102400 bytes",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reader.ReadInput(strings.NewReader(input), "", tt.opts)
+
+			switch tt.wantErr {
+			case "":
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRes, res.Lines)
+				require.Equal(t, []int64{0, int64(len(long)) + 1}, res.Offsets) // смещения считаются по исходным строкам
+			default:
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadInputTruncateUTF8(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		keep    int // байт строки до пометки TruncatedMarker
+		wantRes string
+	}{
+		{name: "Positive - cut inside a 2-byte rune", input: "жжжж", keep: 5, wantRes: "жж"},
+		{name: "Positive - cut on a rune boundary", input: "жжжж", keep: 4, wantRes: "жж"},
+		{name: "Positive - cut inside a 4-byte rune", input: "a😀b", keep: 3, wantRes: "a"},
+		{name: "Positive - invalid UTF-8 is cut as is", input: "\xff\xff\xff\xff", keep: 2, wantRes: "\xff\xff"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// строка длиннее лимита вместе с пометкой
+			input := tt.input + strings.Repeat(" ", len(reader.TruncatedMarker)) + "\n"
+			res, err := reader.ReadInput(strings.NewReader(input), "", reader.Options{MaxLineSize: tt.keep + len(reader.TruncatedMarker), TruncateLongLines: true})
+			require.NoError(t, err)
+			require.Equal(t, []string{tt.wantRes + reader.TruncatedMarker}, res.Lines)
+			require.Equal(t, reader.IsBinary([]string{tt.input}), reader.IsBinary(res.Lines))
+		})
+	}
+}
+
+func TestReadInputEncoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		encoding string
+		wantRes  []string
+		wantErr  string
+	}{
+		{
+			name:    "Positive - UTF-16LE with BOM",
+			input:   "\xff\xfee\x00r\x00r\x00\n\x00\x16\x04\n\x00",
+			wantRes: []string{"err", "Ж"},
+		},
+		{
+			name:    "Positive - UTF-16BE with BOM",
+			input:   "\xfe\xff\x00o\x00k",
+			wantRes: []string{"ok"},
+		},
+		{
+			name:    "Positive - UTF-8 BOM is dropped",
+			input:   "\xef\xbb\xbfline1\nline2",
+			wantRes: []string{"line1", "line2"},
+		},
+		{
+			name:    "Positive - input after UTF-8 BOM is read as is",
+			input:   "\xef\xbb\xbfcaf\xe9\n",
+			wantRes: []string{"caf\xe9"},
+		},
+		{
+			name:    "Positive - input without BOM is read as is",
+			input:   "caf\xe9\n",
+			wantRes: []string{"caf\xe9"},
+		},
+		{
+			name:     "Positive - Latin-1",
+			input:    "caf\xe9\n",
+			encoding: "latin1",
+			wantRes:  []string{"café"},
+		},
+		{
+			name:     "Positive - UTF-16LE without BOM, odd trailing byte replaced",
+			input:    "o\x00k\x00\n\x00x",
+			encoding: "UTF-16LE",
+			wantRes:  []string{"ok", "\uFFFD"},
+		},
+		{
+			name:     "Positive - invalid UTF-8 replaced",
+			input:    "a\xffb",
+			encoding: "utf-8",
+			wantRes:  []string{"a\uFFFDb"},
+		},
+		{
+			name:     "Negative - unknown encoding",
+			input:    "abc",
+			encoding: "klingon",
+			wantErr:  "unsupported encoding",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reader.ReadInput(nil, createTempFile(t, tt.input, false), reader.Options{Encoding: tt.encoding})
+			switch tt.wantErr {
+			case "":
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRes, res.Lines)
+			default:
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReadInputEncodingOffsets: -b - смещения в файле, а не в перекодированном тексте
+func TestReadInputEncodingOffsets(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		encoding string
+		wantRes  []int64
+		wantErr  string
+	}{
+		{
+			name:    "Positive - without BOM",
+			input:   "line1\nline2\n",
+			wantRes: []int64{0, 6},
+		},
+		{
+			name:    "Positive - UTF-8 BOM belongs to the first line",
+			input:   "\xef\xbb\xbfline1\nline2\nline3",
+			wantRes: []int64{0, 9, 15},
+		},
+		{
+			name:    "Negative - UTF-16 BOM",
+			input:   "\xff\xfeo\x00k\x00",
+			wantErr: "byte offsets(-b) are not supported",
+		},
+		{
+			name:     "Negative - --encoding",
+			input:    "caf\xe9\n",
+			encoding: "latin1",
+			wantErr:  "byte offsets(-b) are not supported",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reader.ReadInput(nil, createTempFile(t, tt.input, false), reader.Options{Encoding: tt.encoding, Offsets: true})
+			switch tt.wantErr {
+			case "":
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRes, res.Offsets)
+			default:
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{name: "Positive - plain text", lines: []string{"line1", "строка 2"}, want: false},
+		{name: "Positive - empty input", lines: nil, want: false},
+		{name: "Positive - NUL byte", lines: []string{"line1", "li\x00ne2"}, want: true},
+		{name: "Positive - invalid UTF-8", lines: []string{"\xff\xfeline1"}, want: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, reader.IsBinary(tt.lines))
 		})
 	}
 }
@@ -0,0 +1,250 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+)
+
+// ExpandSources превращает список источников в список файлов для поиска:
+// - glob-шаблоны(в том числе с "**", см. Glob) мастер раскрывает сам, если файла с таким именем нет;
+// - при -r/-R каталоги обходятся рекурсивно, а имена найденных файлов строятся от корня поиска;
+// - если при -r/-R источники не указаны, обходится текущий каталог, и имена выводятся без префикса "./";
+// - --include/--exclude применяются ко всем файлам, --exclude-dir - ко всем каталогам.
+// Отсутствующий файл, шаблон без совпадений и каталог без -r/-R не прерывают поиск: они попадают в problems
+// как *FileError, а остальные файлы ищутся как обычно. Так же сообщается о каталоге, который не удалось прочитать
+// при обходе, а о циклах по ссылкам при -R - предупреждением *FileWarning
+func ExpandSources(src []string, wp model.WalkParam) (files []string, problems []error) {
+	w := walker{
+		param:   wp,
+		visited: make(map[string]struct{}),
+		files:   make([]string, 0, len(src)),
+	}
+
+	if wp.Recursive && len(src) == 0 {
+		w.walkDir(".", true)
+		return w.files, w.problems
+	}
+
+	for _, v := range src {
+		for _, root := range w.expandGlob(v) {
+			w.walkRoot(root)
+		}
+	}
+	return w.files, w.problems
+}
+
+// FileError - проблема с одним из входных файлов; текст как у GNU grep: "путь: причина"
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	switch {
+	case errors.Is(e.Err, fs.ErrNotExist):
+		return e.Path + ": No such file or directory"
+	case errors.Is(e.Err, fs.ErrPermission):
+		return e.Path + ": Permission denied"
+	}
+	var pe *fs.PathError
+	if errors.As(e.Err, &pe) {
+		return e.Path + ": " + pe.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileWarning - предупреждение о входном файле, как у GNU grep("путь: warning: причина"): сообщается так же,
+// как FileError, но на код выхода не влияет
+type FileWarning struct {
+	Path string
+	Msg  string
+}
+
+func (w *FileWarning) Error() string {
+	return w.Path + ": warning: " + w.Msg
+}
+
+// errIsDirectory - каталог указан без -r/-R
+var errIsDirectory = errors.New("Is a directory")
+
+type walker struct {
+	param    model.WalkParam
+	visited  map[string]struct{} // реальные пути каталогов текущей ветки обхода - защита от циклов по ссылкам при -R
+	files    []string
+	problems []error
+}
+
+// expandGlob раскрывает glob-шаблон; путь без метасимволов или существующий файл с таким именем остается как есть
+func (w *walker) expandGlob(src string) []string {
+	if !hasGlobMeta(src) {
+		return []string{src}
+	}
+	if _, err := os.Lstat(src); err == nil {
+		return []string{src}
+	}
+	matches, err := Glob(src)
+	if err != nil || len(matches) == 0 {
+		if w.param.KeepMissing { // --follow: файл еще может появиться - пусть шаблон ждет как обычное имя
+			return []string{src}
+		}
+		w.problems = append(w.problems, &FileError{Path: src, Err: fs.ErrNotExist})
+		return nil
+	}
+	return matches
+}
+
+func (w *walker) walkRoot(root string) {
+	// ссылки, указанные в аргументах, разыменовываются и при -r
+	info, err := os.Stat(root)
+	switch {
+	case err != nil && w.param.KeepMissing && errors.Is(err, fs.ErrNotExist):
+		if w.fileAllowed(root) {
+			w.files = append(w.files, root)
+		}
+		return
+	case err != nil:
+		w.problems = append(w.problems, &FileError{Path: root, Err: err})
+		return
+	}
+
+	if !info.IsDir() {
+		if w.fileAllowed(root) {
+			w.files = append(w.files, root)
+		}
+		return
+	}
+
+	if !w.param.Recursive {
+		w.problems = append(w.problems, &FileError{Path: root, Err: errIsDirectory})
+		return
+	}
+	if root != "." && matchAny(w.param.ExcludeDir, root) {
+		return
+	}
+	w.walkDir(root, false)
+}
+
+func (w *walker) walkDir(dir string, implicit bool) {
+	if realPath, err := filepath.EvalSymlinks(dir); err == nil {
+		if _, ok := w.visited[realPath]; ok {
+			w.problems = append(w.problems, &FileWarning{Path: dir, Msg: "recursive directory loop"})
+			return
+		}
+		w.visited[realPath] = struct{}{}
+		defer delete(w.visited, realPath)
+	}
+
+	// нечитаемый каталог - проблема только с ним: обход продолжается, а прочитанные до ошибки записи обрабатываются
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.problems = append(w.problems, &FileError{Path: dir, Err: err})
+	}
+
+	for _, e := range entries {
+		path := joinPath(dir, e.Name(), implicit)
+
+		isDir := e.IsDir()
+		isRegular := e.Type().IsRegular()
+		if e.Type()&fs.ModeSymlink != 0 {
+			if !w.param.FollowSymlinks { // -r: ссылки, найденные при обходе, пропускаются
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil { // битая ссылка
+				w.problems = append(w.problems, &FileError{Path: path, Err: err})
+				continue
+			}
+			isDir = info.IsDir()
+			isRegular = info.Mode().IsRegular()
+		}
+
+		switch {
+		case isDir:
+			if matchAny(w.param.ExcludeDir, path) {
+				continue
+			}
+			w.walkDir(path, implicit)
+		case isRegular: // устройства, каналы и сокеты при обходе пропускаются
+			if w.fileAllowed(path) {
+				w.files = append(w.files, path)
+			}
+		}
+	}
+}
+
+// fileAllowed проверяет имя файла по --include/--exclude
+func (w *walker) fileAllowed(path string) bool {
+	if matchAny(w.param.Exclude, path) {
+		return false
+	}
+	return len(w.param.Include) == 0 || matchAny(w.param.Include, path)
+}
+
+// matchAny сверяет с glob-ами как базовое имя, так и полный путь
+func matchAny(globs []string, path string) bool {
+	base := filepath.Base(path)
+	for _, g := range globs {
+		if ok, _ := filepath.Match(g, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(g, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath в отличие от filepath.Join сохраняет корень поиска в том виде, в каком он указан("./dir/file"),
+// и только при неявном корне "." отдает путь без префикса
+func joinPath(dir, name string, implicit bool) string {
+	if implicit {
+		return filepath.Join(dir, name)
+	}
+	return strings.TrimRight(dir, string(os.PathSeparator)) + string(os.PathSeparator) + name
+}
+
+// ReadFileList читает список файлов для --files-from(имена разделены переводом строки) или
+// --files0-from(nul - имена разделены NUL-байтом); "-" - читать список из stdin. Пустые имена пропускаются
+func ReadFileList(stdIn io.Reader, name string, nul bool) ([]string, error) {
+	r := stdIn
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open file list %q: %v", name, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	sep := byte('\n')
+	if nul {
+		sep = 0
+	}
+	var files []string
+	br := bufio.NewReader(r)
+	for {
+		entry, err := br.ReadBytes(sep)
+		if entry = bytes.TrimSuffix(entry, []byte{sep}); len(entry) != 0 {
+			files = append(files, string(entry))
+		}
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read file list %q: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package reader_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandSources(t *testing.T) {
+	root := createTempTree(t)
+
+	cases := []struct {
+		name         string
+		src          []string
+		wp           model.WalkParam
+		wantFiles    []string
+		wantProblems []string
+	}{
+		{
+			name:         "Positive - no recursion, missing files and directories are reported",
+			src:          []string{root + "/a.log", root + "/sub", root + "/unreal"},
+			wp:           model.WalkParam{},
+			wantFiles:    []string{root + "/a.log"},
+			wantProblems: []string{root + "/sub: Is a directory", root + "/unreal: No such file or directory"},
+		},
+		{
+			name:      "Positive - no recursion, exclude applies to command-line files",
+			src:       []string{root + "/a.log", root + "/b.txt"},
+			wp:        model.WalkParam{Exclude: model.GlobList{"*.txt"}},
+			wantFiles: []string{root + "/a.log"},
+		},
+		{
+			name:      "Positive - glob with '**'",
+			src:       []string{root + "/**/*.log"},
+			wp:        model.WalkParam{},
+			wantFiles: []string{root + "/a.log", root + "/link.log", root + "/sub/c.log", root + "/vendor/d.log"},
+		},
+		{
+			name:         "Positive - glob without matches",
+			src:          []string{root + "/*.gz", root + "/b.txt"},
+			wp:           model.WalkParam{},
+			wantFiles:    []string{root + "/b.txt"},
+			wantProblems: []string{root + "/*.gz: No such file or directory"},
+		},
+		{
+			name:      "Positive - missing file kept for --follow",
+			src:       []string{root + "/later.log"},
+			wp:        model.WalkParam{KeepMissing: true},
+			wantFiles: []string{root + "/later.log"},
+		},
+		{
+			name:      "Positive - recursive, links inside the tree are skipped",
+			src:       []string{root},
+			wp:        model.WalkParam{Recursive: true},
+			wantFiles: []string{root + "/a.log", root + "/b.txt", root + "/sub/c.log", root + "/vendor/d.log"},
+		},
+		{
+			name: "Positive - recursive, follow all links",
+			src:  []string{root},
+			wp:   model.WalkParam{Recursive: true, FollowSymlinks: true},
+			wantFiles: []string{
+				root + "/a.log", root + "/b.txt", root + "/link.log",
+				root + "/sub/c.log", root + "/sublink/c.log", root + "/vendor/d.log",
+			},
+		},
+		{
+			name:      "Positive - recursive with include, exclude and exclude-dir",
+			src:       []string{root},
+			wp:        model.WalkParam{Recursive: true, Include: model.GlobList{"*.log"}, Exclude: model.GlobList{"a.*"}, ExcludeDir: model.GlobList{"vendor"}},
+			wantFiles: []string{root + "/sub/c.log"},
+		},
+		{
+			name:      "Positive - recursive, single file operand",
+			src:       []string{root + "/b.txt"},
+			wp:        model.WalkParam{Recursive: true},
+			wantFiles: []string{root + "/b.txt"},
+		},
+		{
+			name:         "Negative - recursive, root not found",
+			src:          []string{root + "/unreal", root + "/sub"},
+			wp:           model.WalkParam{Recursive: true},
+			wantFiles:    []string{root + "/sub/c.log"},
+			wantProblems: []string{root + "/unreal: No such file or directory"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, problems := reader.ExpandSources(tt.src, tt.wp)
+			require.Equal(t, tt.wantFiles, res)
+
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.Error())
+			}
+			require.Equal(t, tt.wantProblems, got)
+		})
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		nul     bool
+		wantRes []string
+	}{
+		{
+			name:    "Positive - newline separated, empty lines skipped",
+			content: "a.log\n\nlogs/b c.log\n",
+			wantRes: []string{"a.log", "logs/b c.log"},
+		},
+		{
+			name:    "Positive - NUL separated names with newlines",
+			content: "a.log\x00odd\nname\x00",
+			nul:     true,
+			wantRes: []string{"a.log", "odd\nname"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reader.ReadFileList(strings.NewReader(tt.content), "-", tt.nul)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRes, res)
+
+			res, err = reader.ReadFileList(nil, createTempFile(t, tt.content, false), tt.nul)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRes, res)
+		})
+	}
+}
+
+func TestExpandSourcesImplicitRoot(t *testing.T) {
+	root := createTempTree(t)
+	t.Chdir(filepath.Join(root, "sub"))
+
+	res, problems := reader.ExpandSources(nil, model.WalkParam{Recursive: true})
+
+	require.Empty(t, problems)
+	require.Equal(t, []string{"c.log"}, res)
+}
+
+// TestExpandSourcesWalkProblems: проблемы при обходе -R касаются только своего пути - обход продолжается
+func TestExpandSourcesWalkProblems(t *testing.T) {
+	root := createTempTree(t)
+	require.NoError(t, os.Symlink(root, filepath.Join(root, "sub", "loop")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "unreal"), filepath.Join(root, "broken.log")))
+	locked := filepath.Join(root, "locked")
+	require.NoError(t, os.Mkdir(locked, 0o000))
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o755) })
+
+	res, problems := reader.ExpandSources([]string{root}, model.WalkParam{Recursive: true, FollowSymlinks: true, ExcludeDir: model.GlobList{"sublink"}})
+	require.Equal(t, []string{root + "/a.log", root + "/b.txt", root + "/link.log", root + "/sub/c.log", root + "/vendor/d.log"}, res)
+
+	var got []string
+	var warnings int
+	for _, p := range problems {
+		got = append(got, p.Error())
+		var fw *reader.FileWarning
+		if errors.As(p, &fw) {
+			warnings++
+		}
+	}
+	want := []string{root + "/broken.log: No such file or directory"}
+	if os.Geteuid() != 0 { // root читает каталог и без прав
+		want = append(want, root+"/locked: Permission denied")
+	}
+	want = append(want, root+"/sub/loop: warning: recursive directory loop")
+	require.Equal(t, want, got)
+	require.Equal(t, 1, warnings)
+}
+
+// вспомогательная функция для создания дерева каталогов:
+// a.log, b.txt, link.log -> a.log, sub/c.log, sublink -> sub, vendor/d.log
+func createTempTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.log", "b.txt", "sub/c.log", "vendor/d.log"} {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("line\n"), 0o644))
+	}
+	require.NoError(t, os.Symlink(filepath.Join(root, "a.log"), filepath.Join(root, "link.log")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "sub"), filepath.Join(root, "sublink")))
+	return root
+}
@@ -0,0 +1,236 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport/grpcpb"
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Области действия токенов: проверка доступности и отправка заданий разрешаются отдельно
+const (
+	ScopePing = "ping" // GET /ping, gRPC Health
+	ScopeTask = "task" // POST /task, gRPC Task и TaskStream
+)
+
+var (
+	errNoToken   = errors.New("missing or invalid bearer token")
+	errForbidden = errors.New("token is not allowed to access this endpoint")
+
+	errTokenWithoutTLS = errors.New("refusing to send the auth token to a slave-node without https://")
+)
+
+// TokenStore - токены slave-ноды из файла. Файл перечитывается, как только меняются его время изменения
+// или размер, поэтому токены можно менять без перезапуска; если новый файл прочитать не удалось,
+// продолжают действовать прежние токены. Формат файла - по токену в строке со списком областей через запятую:
+//
+//	# комментарий
+//	3f9c...e1 ping,task
+//	7a01...9b ping
+type TokenStore struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	tokens  map[[sha256.Size]byte][]string // по хешу токена: сравнение не зависит от того, сколько символов совпало
+}
+
+// LoadTokenStore читает файл токенов; ошибка первого чтения не дает запустить ноду
+func LoadTokenStore(path string) (*TokenStore, error) {
+	ts := &TokenStore{path: path}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth tokens: %w", err)
+	}
+	if err := ts.load(fi); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Authorize проверяет, что токен известен и у него есть область scope
+func (ts *TokenStore) Authorize(token, scope string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.reloadIfChanged()
+	if token == "" {
+		return errNoToken
+	}
+	scopes, ok := ts.tokens[sha256.Sum256([]byte(token))]
+	switch {
+	case !ok:
+		return errNoToken
+	case !containsScope(scopes, scope):
+		return fmt.Errorf("%w: token has no %q scope", errForbidden, scope)
+	default:
+		return nil
+	}
+}
+
+func (ts *TokenStore) reloadIfChanged() {
+	fi, err := os.Stat(ts.path)
+	if err != nil {
+		log.Printf("Failed to check auth tokens file, keeping previous tokens: %v", err)
+		return
+	}
+	if fi.ModTime().Equal(ts.modTime) && fi.Size() == ts.size {
+		return
+	}
+	if err := ts.load(fi); err != nil {
+		log.Printf("Failed to reload auth tokens, keeping previous tokens: %v", err)
+		return
+	}
+	log.Printf("Auth tokens reloaded from %q", ts.path)
+}
+
+func (ts *TokenStore) load(fi os.FileInfo) error {
+	raw, err := os.ReadFile(ts.path)
+	if err != nil {
+		return fmt.Errorf("failed to read auth tokens: %w", err)
+	}
+	tokens, err := parseTokens(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse auth tokens %q: %w", ts.path, err)
+	}
+	ts.tokens, ts.modTime, ts.size = tokens, fi.ModTime(), fi.Size()
+	return nil
+}
+
+func parseTokens(raw []byte) (map[[sha256.Size]byte][]string, error) {
+	tokens := make(map[[sha256.Size]byte][]string)
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected 'TOKEN SCOPE[,SCOPE]'", n)
+		}
+		scopes := strings.Split(fields[1], ",")
+		for _, s := range scopes {
+			if s != ScopePing && s != ScopeTask {
+				return nil, fmt.Errorf("line %d: unknown scope %q, expected %q or %q", n, s, ScopePing, ScopeTask)
+			}
+		}
+		tokens[sha256.Sum256([]byte(fields[0]))] = scopes
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens found")
+	}
+	return tokens, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken достает токен из значения заголовка "Authorization: Bearer TOKEN"
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// requireScope - middleware gin: без токена или с неизвестным токеном - 401, без нужной области - 403
+func requireScope(ts *TokenStore, scope string) ginext.HandlerFunc {
+	return func(ctx *ginext.Context) {
+		err := ts.Authorize(bearerToken(ctx.GetHeader("Authorization")), scope)
+		switch {
+		case err == nil:
+			ctx.Next()
+		case errors.Is(err, errForbidden):
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			ctx.Header("WWW-Authenticate", `Bearer realm="mygrep"`)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+// grpcScopes - области методов сервиса Grep
+var grpcScopes = map[string]string{
+	grpcpb.Grep_Health_FullMethodName:     ScopePing,
+	grpcpb.Grep_Task_FullMethodName:       ScopeTask,
+	grpcpb.Grep_TaskStream_FullMethodName: ScopeTask,
+}
+
+func authorizeGRPC(ctx context.Context, ts *TokenStore, method string) error {
+	scope, ok := grpcScopes[method]
+	if !ok {
+		return status.Error(codes.PermissionDenied, "unknown method")
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) != 0 {
+			token = bearerToken(v[0])
+		}
+	}
+	err := ts.Authorize(token, scope)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+}
+
+// authInterceptors - проверка токенов gRPC-сервера, аналог requireScope
+func authInterceptors(ts *TokenStore) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authorizeGRPC(ctx, ts, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorizeGRPC(ss.Context(), ts, info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// tokenCredentials добавляет токен мастера к каждому gRPC-вызову
+type tokenCredentials string
+
+func (tc tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(tc)}, nil
+}
+
+// RequireTransportSecurity - токен передается только по TLS
+func (tc tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,195 @@
+package transport_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testTokens = `# токены мастеров
+all-token ping,task
+ping-token ping
+`
+
+func TestAuth(t *testing.T) {
+	cases := []struct {
+		name        string
+		token       string
+		wantPingErr bool
+		wantTaskErr bool
+	}{
+		{name: "Negative - no token", token: "", wantPingErr: true, wantTaskErr: true},
+		{name: "Negative - unknown token", token: "guess", wantPingErr: true, wantTaskErr: true},
+		{name: "Negative - ping scope only", token: "ping-token", wantTaskErr: true},
+		{name: "Positive - ping and task scopes", token: "all-token"},
+	}
+
+	store, err := transport.LoadTokenStore(writeTokens(t, testTokens))
+	require.NoError(t, err)
+	serverConf, clientConf := testTLSConfigs(t)
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{Auth: store, TLS: serverConf})
+		for _, tt := range cases {
+			t.Run(kind+" "+tt.name, func(t *testing.T) {
+				client, err := transport.NewClient(kind, transport.ClientOptions{Token: tt.token, TLS: clientConf})
+				require.NoError(t, err)
+				defer client.Close()
+
+				_, err = client.Ping(context.Background(), addr)
+				require.Equal(t, tt.wantPingErr, err != nil, "ping error: %v", err)
+
+				task := &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}}
+				res, err := client.SendTask(context.Background(), addr, task)
+				require.Equal(t, tt.wantTaskErr, err != nil, "task error: %v", err)
+				require.Equal(t, tt.wantTaskErr, transport.IsRejected(err), "task error: %v", err)
+				if err == nil {
+					require.Equal(t, []string{"a", "", "p"}, res.Output)
+				}
+
+				if streamer, ok := client.(transport.TaskStreamer); ok {
+					err = streamer.StreamTasks(context.Background(), addr, []*model.MasterTask{{Task: *task, CTX: context.Background()}}, func(*model.SlaveResult) {}, func(string, error) {})
+					require.Equal(t, tt.wantTaskErr, err != nil, "stream error: %v", err)
+				}
+			})
+		}
+	}
+}
+
+// TestAuthTokenRequiresTLS: ноде без https:// токен не отправляется - запрос не выполняется вовсе
+func TestAuthTokenRequiresTLS(t *testing.T) {
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run(kind, func(t *testing.T) {
+			addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{})
+			client, err := transport.NewClient(kind, transport.ClientOptions{Token: "all-token"})
+			require.NoError(t, err)
+			defer client.Close()
+
+			_, err = client.Ping(context.Background(), addr)
+			require.ErrorContains(t, err, "refusing to send the auth token")
+			_, err = client.SendTask(context.Background(), addr, &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}})
+			require.ErrorContains(t, err, "refusing to send the auth token")
+			require.False(t, transport.IsRejected(err))
+		})
+	}
+}
+
+func TestAuthStatus(t *testing.T) {
+	store, err := transport.LoadTokenStore(writeTokens(t, testTokens))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		path     string
+		header   string
+		wantCode int
+		wantGRPC codes.Code
+	}{
+		{name: "Negative - no token", path: "/ping", wantCode: http.StatusUnauthorized, wantGRPC: codes.Unauthenticated},
+		{name: "Negative - not a bearer token", path: "/ping", header: "Basic all-token", wantCode: http.StatusUnauthorized, wantGRPC: codes.Unauthenticated},
+		{name: "Negative - ping token on task", path: "/task", header: "Bearer ping-token", wantCode: http.StatusForbidden, wantGRPC: codes.PermissionDenied},
+		{name: "Positive - ping token on ping", path: "/ping", header: "bearer ping-token", wantCode: http.StatusOK, wantGRPC: codes.OK},
+	}
+
+	serverConf, clientConf := testTLSConfigs(t)
+	grpcAddr := startSlave(t, model.TransportGRPC, echoProcessor, transport.ServerOptions{Auth: store, TLS: serverConf})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{Auth: store})
+			method := "GET"
+			if tt.path == "/task" {
+				method = "POST"
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusUnauthorized {
+				require.Equal(t, `Bearer realm="mygrep"`, w.Header().Get("WWW-Authenticate"))
+			}
+
+			// тот же токен в gRPC-клиенте: схему "Bearer" подставляет клиент, поэтому другие схемы не проверить
+			if token, ok := strings.CutPrefix(tt.header, "Bearer "); ok || tt.header == "" {
+				client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{Token: token, TLS: clientConf})
+				require.NoError(t, err)
+				defer client.Close()
+				if tt.path == "/ping" {
+					_, err = client.Ping(context.Background(), grpcAddr)
+				} else {
+					_, err = client.SendTask(context.Background(), grpcAddr, &model.TaskDTO{GP: model.GrepParam{Pattern: "p"}})
+				}
+				require.Equal(t, tt.wantGRPC, status.Code(err), "error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenStoreReload(t *testing.T) {
+	path := writeTokens(t, "old-token ping,task\n")
+	store, err := transport.LoadTokenStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Authorize("old-token", transport.ScopeTask))
+
+	// ротация: новый файл подхватывается без перезапуска
+	require.NoError(t, os.WriteFile(path, []byte("new-token-2 task\n"), 0o600))
+	require.Error(t, store.Authorize("old-token", transport.ScopeTask))
+	require.NoError(t, store.Authorize("new-token-2", transport.ScopeTask))
+	require.Error(t, store.Authorize("new-token-2", transport.ScopePing))
+
+	// испорченный файл не отзывает действующие токены
+	require.NoError(t, os.WriteFile(path, []byte("broken-line-without-scopes\n"), 0o600))
+	require.NoError(t, store.Authorize("new-token-2", transport.ScopeTask))
+}
+
+func TestLoadTokenStore(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "Positive - comments and empty lines", content: "# c\n\n tok ping \n"},
+		{name: "Negative - no tokens", content: "# only a comment\n", wantErr: true},
+		{name: "Negative - unknown scope", content: "tok ping,admin\n", wantErr: true},
+		{name: "Negative - token without scopes", content: "tok\n", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := transport.LoadTokenStore(writeTokens(t, tt.content))
+			require.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+
+	_, err := transport.LoadTokenStore(filepath.Join(t.TempDir(), "unreal"))
+	require.Error(t, err)
+}
+
+// testTLSConfigs - TLS slave-ноды и мастера с общим CA: токены отправляются только нодам с https://
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	pki := newTestPKI(t)
+	server, err := transport.ServerTLSConfig(model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey})
+	require.NoError(t, err)
+	client, err = transport.ClientTLSConfig(model.TLSParam{CAFile: pki.ca})
+	require.NoError(t, err)
+	return server, client
+}
+
+func writeTokens(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
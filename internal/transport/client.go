@@ -0,0 +1,228 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+)
+
+// Client - сторона мастера: проверка доступности и возможностей slave-ноды и отправка ей заданий
+type Client interface {
+	Ping(ctx context.Context, addr string) (*model.NodeInfo, error)
+	SendTask(ctx context.Context, addr string, task *model.TaskDTO) (*model.SlaveResult, error)
+	Close() error
+}
+
+// TaskStreamer - клиент, который умеет отправить slave-ноде много заданий по одному соединению(gRPC TaskStream).
+// onResult вызывается для каждого результата по мере готовности, не по порядку заданий, onReject - для задания,
+// от которого нода отказалась(ошибка, как у SendTask, IsRejected). Задание, CTX которого отменен до результата,
+// отменяется и на ноде; когда у всех заданий есть результат, отказ или отмена, поток закрывается
+type TaskStreamer interface {
+	StreamTasks(ctx context.Context, addr string, tasks []*model.MasterTask, onResult func(*model.SlaveResult),
+		onReject func(taskID string, err error)) error
+}
+
+// ClientOptions - настройки клиента мастера, общие для обоих транспортов
+type ClientOptions struct {
+	TLS                 *tls.Config // см. ClientTLSConfig; используется для нод с адресом https://
+	Token               string      // bearer-токен для slave-нод с проверкой токенов; пусто - не отправляется. Отправляется только по https://
+	Compress            bool        // сжимать задания gzip и просить сжатые результаты - у нод с model.FeatureGzip в Ping
+	MaxDecompressedSize int64       // ограничение результата после распаковки; 0 - DefaultMaxDecompressedSize
+	Metrics             *Metrics    // счетчики сжатых и несжатых байт; nil - не нужны снаружи
+}
+
+// NewClient создает клиент мастера для транспорта kind(model.TransportHTTP или model.TransportGRPC)
+func NewClient(kind string, opts ClientOptions) (Client, error) {
+	if opts.TLS == nil {
+		opts.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &Metrics{}
+	}
+	switch kind {
+	case model.TransportHTTP, "":
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = opts.TLS
+		tr.DisableCompression = true // сжатие согласует сам httpClient по opts.Compress
+		return &httpClient{client: &http.Client{Timeout: 5 * time.Second, Transport: tr}, opts: opts}, nil
+	case model.TransportGRPC:
+		return newGRPCClient(opts), nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", kind)
+	}
+}
+
+// nodeFeatures - возможности slave-нод из последнего ответа на Ping: по ним клиент решает, сжимать ли задания
+// (model.FeatureGzip) и отменять ли задания потока(model.FeatureStreamCancel). Нода, которая еще не отвечала
+// на Ping, считается нодой без этих возможностей
+type nodeFeatures struct {
+	m sync.Map
+}
+
+func (nf *nodeFeatures) update(addr string, info *model.NodeInfo) {
+	nf.m.Store(addr, info.Features)
+}
+
+func (nf *nodeFeatures) has(addr, feature string) bool {
+	features, ok := nf.m.Load(addr)
+	return ok && slices.Contains(features.([]string), feature)
+}
+
+type httpClient struct {
+	client *http.Client
+	opts   ClientOptions
+	nodes  nodeFeatures
+}
+
+// httpAddr добавляет к адресу ноды схему http://, если схема не указана
+func httpAddr(addr string) string {
+	if !strings.HasPrefix(addr, schemeHTTP) && !IsTLSAddr(addr) {
+		return schemeHTTP + addr
+	}
+	return addr
+}
+
+func (hc *httpClient) Ping(ctx context.Context, addr string) (*model.NodeInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", httpAddr(addr)+"/ping", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := hc.authorize(req, addr); err != nil {
+		return nil, err
+	}
+
+	resp, err := hc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	// старые slave-ноды отвечают пустым телом
+	var info model.NodeInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&info); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to UNMARSHAL node info: %w", err)
+	}
+	hc.nodes.update(addr, &info)
+	return legacyNodeInfo(&info), nil
+}
+
+func (hc *httpClient) SendTask(ctx context.Context, addr string, task *model.TaskDTO) (*model.SlaveResult, error) {
+	// сразу маршалим задание на отправку
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to MARSHAL task: %w", err)
+	}
+
+	resp, err := hc.postTask(ctx, addr, raw, hc.opts.Compress && hc.nodes.has(addr, model.FeatureGzip))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := hc.decodeBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to DECOMPRESS result: %w", err)
+	}
+	// ошибка(например, 401 без токена) приходит в другом формате - не принимаем ее за пустой результат
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(body, 1<<10))
+		te := &TaskError{Status: resp.StatusCode}
+		if json.Unmarshal(msg, te) != nil || te.Message == "" {
+			te.Message = string(bytes.TrimSpace(msg))
+		}
+		return nil, fmt.Errorf("task rejected with status %q: %w", resp.Status, te)
+	}
+
+	var result model.SlaveResult
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to UNMARSHAL result: %w", err)
+	}
+	return &result, nil
+}
+
+// postTask отправляет задание raw, при compress - сжатое gzip и с просьбой сжать результат
+func (hc *httpClient) postTask(ctx context.Context, addr string, raw []byte, compress bool) (*http.Response, error) {
+	payload := raw
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return nil, fmt.Errorf("failed to COMPRESS task: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to COMPRESS task: %w", err)
+		}
+		payload = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", httpAddr(addr)+"/task", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to GENERATE request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+		// заголовок выставлен явно, поэтому http.Transport не распаковывает ответ сам - это делает decodeBody
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if err := hc.authorize(req, addr); err != nil {
+		return nil, err
+	}
+
+	resp, err := hc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to SEND task: %w", err)
+	}
+	hc.opts.Metrics.SentRaw.Add(int64(len(raw)))
+	hc.opts.Metrics.SentWire.Add(int64(len(payload)))
+	return resp, nil
+}
+
+// decodeBody распаковывает ответ по Content-Encoding и ограничивает его размер после распаковки
+func (hc *httpClient) decodeBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = &countingReader{r: resp.Body, n: &hc.opts.Metrics.ReceivedWire}
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		body = zr
+	}
+	body = &limitedReader{r: body, left: hc.opts.MaxDecompressedSize, limit: hc.opts.MaxDecompressedSize}
+	return &countingReader{r: body, n: &hc.opts.Metrics.ReceivedRaw}, nil
+}
+
+// authorize добавляет токен к запросу; ноде без https:// токен не отправляется - запрос не выполняется вовсе
+func (hc *httpClient) authorize(req *http.Request, addr string) error {
+	if hc.opts.Token == "" {
+		return nil
+	}
+	if !IsTLSAddr(addr) {
+		return fmt.Errorf("%w: %q", errTokenWithoutTLS, addr)
+	}
+	req.Header.Set("Authorization", "Bearer "+hc.opts.Token)
+	return nil
+}
+
+func (hc *httpClient) Close() error {
+	hc.client.CloseIdleConnections()
+	return nil
+}
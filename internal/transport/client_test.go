@@ -0,0 +1,300 @@
+package transport_test
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProcessor возвращает в результате содержимое задания - по нему видно, что задание дошло без потерь
+var echoProcessor = mockProcessor{
+	returnResultFn: func(ctx context.Context, task *model.SlaveTask) *model.SlaveResult {
+		res := &model.SlaveResult{
+			TaskID:   task.TaskID,
+			HashSumm: uint64(len(task.Input)),
+			Output:   append(append([]string{}, task.Input...), task.FileName, task.GP.Pattern),
+			Node:     "n1",
+			Errors:   task.Paths,
+		}
+		if task.GP.MaxCount != nil {
+			res.Selected = *task.GP.MaxCount
+		}
+		if task.GP.GroupSeparator != nil {
+			res.Output = append(res.Output, "sep="+*task.GP.GroupSeparator)
+		}
+		if task.GP.Color {
+			res.Meta = []model.LineMeta{{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 1, End: 3}}}}
+		}
+		return res
+	},
+}
+
+// startSlave запускает slave-ноду с транспортом kind и возвращает ее адрес(с TLS - https://)
+func startSlave(t *testing.T, kind string, p transport.TaskProcessor, opts transport.ServerOptions) string {
+	t.Helper()
+	switch kind {
+	case model.TransportGRPC:
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		srv := transport.NewGRPCServer(p, opts)
+		go func() { _ = srv.Serve(lis) }()
+		t.Cleanup(srv.Stop)
+		if opts.TLS != nil {
+			return "https://" + lis.Addr().String()
+		}
+		return lis.Addr().String()
+	default:
+		srv := httptest.NewUnstartedServer(transport.NewSlaveServer("", p, opts).Handler)
+		if opts.TLS != nil {
+			srv.TLS = opts.TLS
+			srv.StartTLS()
+		} else {
+			srv.Start()
+		}
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+}
+
+func TestClientSendTask(t *testing.T) {
+	maxCount := 3
+	emptySep := ""
+	cases := []struct {
+		name    string
+		task    model.TaskDTO
+		wantRes *model.SlaveResult
+	}{
+		{
+			name: "Positive - plain task",
+			task: model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "a.c"}, Input: []string{"abc", ""}, FileName: "f.log"},
+			wantRes: &model.SlaveResult{
+				TaskID: "t1", HashSumm: 2, Output: []string{"abc", "", "f.log", "a.c"}, Node: "n1",
+			},
+		},
+		{
+			name: "Positive - NUL bytes, -m, empty group separator and color meta",
+			task: model.TaskDTO{
+				TaskID: "t2",
+				GP:     model.GrepParam{Pattern: "x", MaxCount: &maxCount, GroupSeparator: &emptySep, Color: true},
+				Input:  []string{"\x00é x"},
+			},
+			wantRes: &model.SlaveResult{
+				TaskID: "t2", HashSumm: 1, Output: []string{"\x00é x", "", "x", "sep="}, Selected: 3, Node: "n1",
+				Meta: []model.LineMeta{{Kind: model.LineSelected, Prefix: 2, Spans: []model.Span{{Start: 1, End: 3}}}},
+			},
+		},
+		{
+			name: "Positive - invalid UTF-8 is delivered byte-exact",
+			task: model.TaskDTO{TaskID: "t5", GP: model.GrepParam{Pattern: "b"}, Input: []string{"ok", "a\xffb", "\xe2\x82"}},
+			wantRes: &model.SlaveResult{
+				TaskID: "t5", HashSumm: 3, Output: []string{"ok", "a\xffb", "\xe2\x82", "", "b"}, Node: "n1",
+			},
+		},
+		{
+			name: "Positive - line longer than 4 MiB",
+			task: model.TaskDTO{TaskID: "t3", GP: model.GrepParam{Pattern: "a"}, Input: []string{strings.Repeat("a", 5<<20)}},
+			wantRes: &model.SlaveResult{
+				TaskID: "t3", HashSumm: 1, Output: []string{strings.Repeat("a", 5<<20), "", "a"}, Node: "n1",
+			},
+		},
+		{
+			name: "Positive - remote paths",
+			task: model.TaskDTO{TaskID: "t4", GP: model.GrepParam{Pattern: "a", Remote: true}, Input: []string{}, Paths: []string{"logs/*.log"}},
+			wantRes: &model.SlaveResult{
+				TaskID: "t4", Output: []string{"", "a"}, Node: "n1", Errors: []string{"logs/*.log"},
+			},
+		},
+	}
+
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{})
+		client, err := transport.NewClient(kind, transport.ClientOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+
+		for _, tt := range cases {
+			t.Run(kind+" "+tt.name, func(t *testing.T) {
+				_, err := client.Ping(context.Background(), addr)
+				require.NoError(t, err)
+
+				res, err := client.SendTask(context.Background(), addr, &tt.task)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRes.Output, res.Output)
+				require.Equal(t, tt.wantRes.Meta, res.Meta)
+				require.Equal(t, tt.wantRes.Selected, res.Selected)
+				require.Equal(t, tt.wantRes.HashSumm, res.HashSumm)
+				require.Equal(t, tt.wantRes.TaskID, res.TaskID)
+				require.Equal(t, tt.wantRes.Node, res.Node)
+				require.ElementsMatch(t, tt.wantRes.Errors, res.Errors)
+			})
+		}
+	}
+}
+
+func TestClientStreamTasks(t *testing.T) {
+	addr := startSlave(t, model.TransportGRPC, echoProcessor, transport.ServerOptions{})
+	client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	streamer, ok := client.(transport.TaskStreamer)
+	require.True(t, ok, "gRPC client must support task streams")
+
+	// строки передаются байт в байт, даже невалидный UTF-8
+	inputs := map[string]string{"t1": "a", "t2": "b", "t3": "", "t4": "\xff\xfe", "t5": "d"}
+	var tasks []*model.MasterTask
+	for id, line := range inputs {
+		tasks = append(tasks, &model.MasterTask{
+			Task: model.TaskDTO{TaskID: id, GP: model.GrepParam{Pattern: "p"}, Input: []string{line}},
+			CTX:  context.Background(),
+		})
+	}
+
+	// от недопустимых заданий нода отказывается по одному, не обрывая поток
+	tasks = append(tasks,
+		&model.MasterTask{Task: model.TaskDTO{TaskID: "bad-pattern", GP: model.GrepParam{Pattern: "a("}, Input: []string{"a"}}, CTX: context.Background()},
+		&model.MasterTask{Task: model.TaskDTO{TaskID: "bad-feature", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}, Features: []string{"unknown-feature"}}, CTX: context.Background()},
+	)
+
+	var got []string
+	rejected := make(map[string]error)
+	err = streamer.StreamTasks(context.Background(), addr, tasks, func(res *model.SlaveResult) {
+		require.Equal(t, []string{inputs[res.TaskID], "", "p"}, res.Output)
+		got = append(got, res.TaskID)
+	}, func(taskID string, err error) {
+		rejected[taskID] = err
+	})
+	require.NoError(t, err)
+	sort.Strings(got)
+	require.Equal(t, []string{"t1", "t2", "t3", "t4", "t5"}, got)
+
+	require.Len(t, rejected, 2)
+	var te *transport.TaskError
+	require.ErrorAs(t, rejected["bad-pattern"], &te)
+	require.Equal(t, transport.CodeInvalidTask, te.Code)
+	require.Equal(t, "grep_param.pattern", te.Field)
+	require.ErrorContains(t, rejected["bad-pattern"], `status "422 Unprocessable Entity"`)
+	require.ErrorAs(t, rejected["bad-feature"], &te)
+	require.Equal(t, transport.CodeUnsupportedFeature, te.Code)
+	for _, err := range rejected {
+		require.True(t, transport.IsRejected(err), "error: %v", err)
+	}
+}
+
+func TestClientStreamTasksCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan string, 1)
+	newProcessor := func(release <-chan struct{}) mockProcessor {
+		return mockProcessor{returnResultFn: func(ctx context.Context, task *model.SlaveTask) *model.SlaveResult {
+			switch task.TaskID {
+			case "slow": // работает, пока задание не отменят
+				started <- struct{}{}
+				<-ctx.Done()
+				stopped <- task.TaskID
+			case "wait":
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}
+			return &model.SlaveResult{TaskID: task.TaskID, Output: []string{}}
+		}}
+	}
+	wait := func(t *testing.T, ch <-chan string) string {
+		t.Helper()
+		select {
+		case v := <-ch:
+			return v
+		case <-time.After(5 * time.Second):
+			t.Fatal("the slave-node didn't stop the cancelled task")
+			return ""
+		}
+	}
+
+	cases := []struct {
+		name    string
+		ping    bool // после Ping клиент знает, что нода умеет отменять задания потока
+		waitFor bool // в потоке есть задание, которое не отменяется
+	}{
+		{name: "Positive - cancel message while other tasks still run", ping: true, waitFor: true},
+		{name: "Positive - stream closed once every task is cancelled", ping: false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			addr := startSlave(t, model.TransportGRPC, newProcessor(release), transport.ServerOptions{})
+			client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{})
+			require.NoError(t, err)
+			defer client.Close()
+			if tt.ping {
+				_, err = client.Ping(context.Background(), addr)
+				require.NoError(t, err)
+			}
+
+			slowCtx, cancelSlow := context.WithCancel(context.Background())
+			tasks := []*model.MasterTask{{Task: model.TaskDTO{TaskID: "slow", GP: model.GrepParam{Pattern: "p"}, Input: []string{}}, CTX: slowCtx}}
+			if tt.waitFor {
+				tasks = append(tasks, &model.MasterTask{Task: model.TaskDTO{TaskID: "wait", GP: model.GrepParam{Pattern: "p"}, Input: []string{}}, CTX: context.Background()})
+			}
+			var got []string
+			done := make(chan error, 1)
+			go func() {
+				done <- client.(transport.TaskStreamer).StreamTasks(context.Background(), addr, tasks, func(res *model.SlaveResult) {
+					got = append(got, res.TaskID)
+				}, func(taskID string, err error) {
+					t.Errorf("task %s rejected: %v", taskID, err)
+				})
+			}()
+
+			<-started
+			cancelSlow()
+			require.Equal(t, "slow", wait(t, stopped))
+			if tt.waitFor {
+				close(release)
+			}
+			require.NoError(t, <-done)
+			if tt.waitFor {
+				require.Equal(t, []string{"wait"}, got)
+			} else {
+				require.Empty(t, got)
+			}
+		})
+	}
+}
+
+func TestClientPingUnavailable(t *testing.T) {
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run(kind, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			addr := lis.Addr().String()
+			require.NoError(t, lis.Close())
+
+			client, err := transport.NewClient(kind, transport.ClientOptions{})
+			require.NoError(t, err)
+			defer client.Close()
+			_, err = client.Ping(context.Background(), addr)
+			require.Error(t, err)
+
+			// недоступная нода не отказывает в задании - результат могут дать другие ноды
+			_, err = client.SendTask(context.Background(), addr, &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}})
+			require.Error(t, err)
+			require.False(t, transport.IsRejected(err), "error: %v", err)
+		})
+	}
+}
+
+func TestNewServerUnknownTransport(t *testing.T) {
+	_, err := transport.NewServer("udp", "0", echoProcessor, transport.ServerOptions{})
+	require.Error(t, err)
+	_, err = transport.NewClient("udp", transport.ClientOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package transport
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"google.golang.org/grpc/stats"
+)
+
+// DefaultMaxDecompressedSize - сколько байт задания(у slave-ноды) или результата(у мастера) допускается
+// после распаковки: защита от "zip-бомб"
+const DefaultMaxDecompressedSize = 1 << 30
+
+// Metrics - счетчики байт заданий и результатов: Raw - JSON/protobuf до сжатия(после распаковки),
+// Wire - сколько на самом деле передано по сети
+type Metrics struct {
+	SentRaw      atomic.Int64
+	SentWire     atomic.Int64
+	ReceivedRaw  atomic.Int64
+	ReceivedWire atomic.Int64
+}
+
+func (m *Metrics) String() string {
+	return fmt.Sprintf("sent %d bytes(%d on the wire, %s), received %d bytes(%d on the wire, %s)",
+		m.SentRaw.Load(), m.SentWire.Load(), ratio(m.SentRaw.Load(), m.SentWire.Load()),
+		m.ReceivedRaw.Load(), m.ReceivedWire.Load(), ratio(m.ReceivedRaw.Load(), m.ReceivedWire.Load()))
+}
+
+func ratio(raw, wire int64) string {
+	if wire == 0 {
+		return "ratio n/a"
+	}
+	return fmt.Sprintf("ratio %.1fx", float64(raw)/float64(wire))
+}
+
+// WritePrometheus выводит счетчики в текстовом формате Prometheus
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	fmt.Fprintln(w, "# HELP mygrep_payload_bytes_total Task and result payload bytes: raw - uncompressed, wire - as transferred.")
+	fmt.Fprintln(w, "# TYPE mygrep_payload_bytes_total counter")
+	for _, c := range []struct {
+		direction, size string
+		v               *atomic.Int64
+	}{
+		{"sent", "raw", &m.SentRaw},
+		{"sent", "wire", &m.SentWire},
+		{"received", "raw", &m.ReceivedRaw},
+		{"received", "wire", &m.ReceivedWire},
+	} {
+		fmt.Fprintf(w, "mygrep_payload_bytes_total{direction=%q,size=%q} %d\n", c.direction, c.size, c.v.Load())
+	}
+}
+
+// acceptsGzip разбирает Accept-Encoding: gzip принимается, если он(а без него - "*") указан без q=0
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if q, _ = strconv.ParseFloat(v, 64); q < 0 {
+				q = 0
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// compression - middleware /task: тело запроса(не больше opts.Limits.MaxBodySize байт) распаковывается по
+// Content-Encoding(не больше opts.MaxDecompressedSize байт после распаковки - иначе 413), ответ сжимается,
+// если мастер прислал Accept-Encoding: gzip
+func compression(opts ServerOptions) ginext.HandlerFunc {
+	m := opts.Metrics
+	return func(ctx *ginext.Context) {
+		orig := ctx.Request.Body
+		wire := http.MaxBytesReader(ctx.Writer, orig, opts.Limits.MaxBodySize)
+		var body io.Reader = &countingReader{r: wire, n: &m.ReceivedWire}
+		switch enc := strings.ToLower(strings.TrimSpace(ctx.GetHeader("Content-Encoding"))); enc {
+		case "", "identity":
+		case "gzip":
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, &TaskError{Message: "invalid gzip body: " + err.Error(), Code: CodeMalformedTask})
+				return
+			}
+			body = zr
+		default:
+			ctx.Header("Accept-Encoding", "gzip")
+			ctx.AbortWithStatusJSON(http.StatusUnsupportedMediaType, &TaskError{
+				Message: fmt.Sprintf("unsupported Content-Encoding %q", enc),
+				Code:    CodeUnsupportedEncoding,
+			})
+			return
+		}
+		body = &countingReader{r: body, n: &m.ReceivedRaw}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, readCloser{Reader: body, Closer: orig}, opts.MaxDecompressedSize)
+
+		ew := &encodedWriter{ResponseWriter: ctx.Writer, raw: &m.SentRaw}
+		ew.w = countingWriter{w: ctx.Writer, n: &m.SentWire}
+		if acceptsGzip(ctx.GetHeader("Accept-Encoding")) {
+			ctx.Header("Content-Encoding", "gzip")
+			ctx.Header("Vary", "Accept-Encoding")
+			ew.zw = gzip.NewWriter(ew.w)
+			ew.w = ew.zw
+		}
+		ctx.Writer = ew
+		ctx.Next()
+		if ew.zw != nil {
+			_ = ew.zw.Close()
+		}
+	}
+}
+
+// encodedWriter считает байты ответа и при необходимости сжимает их
+type encodedWriter struct {
+	gin.ResponseWriter
+	w   io.Writer
+	zw  *gzip.Writer
+	raw *atomic.Int64
+}
+
+func (ew *encodedWriter) Write(p []byte) (int, error) {
+	ew.raw.Add(int64(len(p)))
+	return ew.w.Write(p)
+}
+
+func (ew *encodedWriter) WriteString(s string) (int, error) {
+	return ew.Write([]byte(s))
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n.Add(int64(n))
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedReader - как http.MaxBytesReader, но для ответов slave-нод: больше limit байт - ошибка, а не обрезка
+type limitedReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > lr.left+1 {
+		p = p[:lr.left+1]
+	}
+	n, err := lr.r.Read(p)
+	if int64(n) <= lr.left {
+		lr.left -= int64(n)
+		return n, err
+	}
+	n, lr.left = int(lr.left), 0
+	return n, fmt.Errorf("payload exceeds %d bytes after decompression", lr.limit)
+}
+
+// metricsHandler считает байты gRPC-сообщений: Length - до сжатия, CompressedLength - после
+type metricsHandler struct {
+	m *Metrics
+}
+
+func (mh metricsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (mh metricsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	switch p := s.(type) {
+	case *stats.InPayload:
+		mh.m.ReceivedRaw.Add(int64(p.Length))
+		mh.m.ReceivedWire.Add(int64(p.CompressedLength))
+	case *stats.OutPayload:
+		mh.m.SentRaw.Add(int64(p.Length))
+		mh.m.SentWire.Add(int64(p.CompressedLength))
+	}
+}
+
+func (mh metricsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (mh metricsHandler) HandleConn(context.Context, stats.ConnStats) {}
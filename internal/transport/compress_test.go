@@ -0,0 +1,248 @@
+package transport_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// repeatedTask - задание из одинаковых строк: сжимается во много раз
+func repeatedTask(lines int) *model.TaskDTO {
+	input := make([]string, lines)
+	for i := range input {
+		input[i] = "2024-01-01 INFO request served in 12ms"
+	}
+	return &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "INFO"}, Input: input}
+}
+
+func TestCompression(t *testing.T) {
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		for _, compress := range []bool{true, false} {
+			name := kind + " without compression"
+			if compress {
+				name = kind + " with gzip"
+			}
+			t.Run(name, func(t *testing.T) {
+				srvMetrics := &transport.Metrics{}
+				addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{Metrics: srvMetrics})
+				cliMetrics := &transport.Metrics{}
+				client, err := transport.NewClient(kind, transport.ClientOptions{Compress: compress, Metrics: cliMetrics})
+				require.NoError(t, err)
+				defer client.Close()
+				_, err = client.Ping(context.Background(), addr)
+				require.NoError(t, err)
+
+				task := repeatedTask(1000)
+				res, err := client.SendTask(context.Background(), addr, task)
+				require.NoError(t, err)
+				require.Equal(t, append(task.Input, "", "INFO"), res.Output)
+
+				require.Positive(t, cliMetrics.SentRaw.Load())
+				require.Positive(t, cliMetrics.ReceivedRaw.Load())
+				require.Equal(t, cliMetrics.SentWire.Load(), srvMetrics.ReceivedWire.Load())
+				require.Equal(t, cliMetrics.ReceivedWire.Load(), srvMetrics.SentWire.Load())
+				if compress {
+					require.Less(t, cliMetrics.SentWire.Load()*10, cliMetrics.SentRaw.Load(), "task: %s", cliMetrics)
+					require.Less(t, cliMetrics.ReceivedWire.Load()*10, cliMetrics.ReceivedRaw.Load(), "result: %s", cliMetrics)
+				} else {
+					require.Equal(t, cliMetrics.SentRaw.Load(), cliMetrics.SentWire.Load())
+					require.Equal(t, cliMetrics.ReceivedRaw.Load(), cliMetrics.ReceivedWire.Load())
+				}
+			})
+		}
+	}
+}
+
+func TestCompressionHTTPStatus(t *testing.T) {
+	taskJSON, err := json.Marshal(repeatedTask(1000))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name           string
+		body           []byte
+		encoding       string
+		accept         string
+		wantCode       int
+		wantGzipResult bool
+	}{
+		{name: "Positive - gzip task, gzip result", body: gzipBytes(t, taskJSON), encoding: "gzip", accept: "gzip", wantCode: http.StatusOK, wantGzipResult: true},
+		{name: "Positive - plain task, any encoding of result", body: taskJSON, accept: "br;q=0.5, *", wantCode: http.StatusOK, wantGzipResult: true},
+		{name: "Positive - gzip refused with q=0", body: taskJSON, accept: "gzip;q=0, *", wantCode: http.StatusOK},
+		{name: "Positive - no Accept-Encoding", body: gzipBytes(t, taskJSON), encoding: "GZIP", wantCode: http.StatusOK},
+		{name: "Negative - unsupported Content-Encoding", body: taskJSON, encoding: "br", wantCode: http.StatusUnsupportedMediaType},
+		{name: "Negative - broken gzip", body: taskJSON, encoding: "gzip", wantCode: http.StatusBadRequest},
+		{name: "Negative - decompression bomb", body: gzipBytes(t, bytes.Repeat([]byte(" "), 1<<20)), encoding: "gzip", wantCode: http.StatusRequestEntityTooLarge},
+	}
+
+	srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{MaxDecompressedSize: 64 << 10})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/task", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			require.Equal(t, tt.wantCode, w.Code, "body: %s", w.Body)
+			if tt.wantCode == http.StatusUnsupportedMediaType {
+				require.Equal(t, "gzip", w.Header().Get("Accept-Encoding"))
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			body := io.Reader(w.Body)
+			if tt.wantGzipResult {
+				require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				zr, err := gzip.NewReader(body)
+				require.NoError(t, err)
+				body = zr
+			} else {
+				require.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+			var res model.SlaveResult
+			require.NoError(t, json.NewDecoder(body).Decode(&res))
+			require.Len(t, res.Output, 1002)
+		})
+	}
+}
+
+func TestCompressionLimits(t *testing.T) {
+	task := repeatedTask(10000)
+
+	t.Run("Negative - gRPC task larger than the slave-node limit", func(t *testing.T) {
+		addr := startSlave(t, model.TransportGRPC, echoProcessor, transport.ServerOptions{MaxDecompressedSize: 64 << 10})
+		client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{Compress: true})
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = client.Ping(context.Background(), addr)
+		require.NoError(t, err)
+		_, err = client.SendTask(context.Background(), addr, task)
+		require.Equal(t, codes.ResourceExhausted, status.Code(err), "error: %v", err)
+	})
+
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run("Negative - "+kind+" result larger than the master limit", func(t *testing.T) {
+			addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{})
+			client, err := transport.NewClient(kind, transport.ClientOptions{Compress: true, MaxDecompressedSize: 64 << 10})
+			require.NoError(t, err)
+			defer client.Close()
+			_, err = client.Ping(context.Background(), addr)
+			require.NoError(t, err)
+			_, err = client.SendTask(context.Background(), addr, task)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCompressionFallback(t *testing.T) {
+	// slave-нода до сжатия: на /ping отвечает пустым телом, сжатое задание не разбирает(400)
+	var compressed, plain atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "" || r.Header.Get("Accept-Encoding") != "" {
+			compressed.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		plain.Add(1)
+		var task model.SlaveTask
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+		_ = json.NewEncoder(w).Encode(echoProcessor.ProcessInput(r.Context(), &task))
+	}))
+	defer srv.Close()
+
+	client, err := transport.NewClient(model.TransportHTTP, transport.ClientOptions{Compress: true})
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Ping(context.Background(), srv.URL)
+	require.NoError(t, err)
+	for range 3 {
+		res, err := client.SendTask(context.Background(), srv.URL, repeatedTask(10))
+		require.NoError(t, err)
+		require.Len(t, res.Output, 12)
+	}
+	require.Zero(t, compressed.Load(), "the slave-node didn't report gzip in /ping")
+	require.Equal(t, int32(3), plain.Load())
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run(kind, func(t *testing.T) {
+			addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{})
+			metrics := &transport.Metrics{}
+			client, err := transport.NewClient(kind, transport.ClientOptions{Compress: true, Metrics: metrics})
+			require.NoError(t, err)
+			defer client.Close()
+
+			// до Ping клиент не знает, умеет ли нода gzip, и не сжимает
+			_, err = client.SendTask(context.Background(), addr, repeatedTask(1000))
+			require.NoError(t, err)
+			require.Equal(t, metrics.SentRaw.Load(), metrics.SentWire.Load())
+
+			info, err := client.Ping(context.Background(), addr)
+			require.NoError(t, err)
+			require.Contains(t, info.Features, model.FeatureGzip)
+			sentRaw, sentWire := metrics.SentRaw.Load(), metrics.SentWire.Load()
+			_, err = client.SendTask(context.Background(), addr, repeatedTask(1000))
+			require.NoError(t, err)
+			require.Less(t, (metrics.SentWire.Load()-sentWire)*10, metrics.SentRaw.Load()-sentRaw)
+		})
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	metrics := &transport.Metrics{}
+	addr := startSlave(t, model.TransportHTTP, echoProcessor, transport.ServerOptions{Metrics: metrics})
+	client, err := transport.NewClient(model.TransportHTTP, transport.ClientOptions{Compress: true})
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Ping(context.Background(), addr)
+	require.NoError(t, err)
+	_, err = client.SendTask(context.Background(), addr, repeatedTask(100))
+	require.NoError(t, err)
+
+	resp, err := http.Get(addr + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	for _, line := range []string{
+		"# TYPE mygrep_payload_bytes_total counter",
+		`mygrep_payload_bytes_total{direction="received",size="raw"} `,
+		`mygrep_payload_bytes_total{direction="sent",size="wire"} `,
+	} {
+		require.Contains(t, string(body), line)
+	}
+	require.True(t, strings.Contains(metrics.String(), "ratio"), metrics.String())
+}
+
+func gzipBytes(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
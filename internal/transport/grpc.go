@@ -0,0 +1,601 @@
+package transport
+
+//go:generate protoc -I grpcpb --go_out=grpcpb --go_opt=paths=source_relative --go-grpc_out=grpcpb --go-grpc_opt=paths=source_relative grep.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport/grpcpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip" // регистрирует gzip: сервер распаковывает задания и сжимает ответы
+)
+
+// maxMsgSize - ограничение размера отправляемого сообщения gRPC: по умолчанию оно 4 МиБ, а задание - это весь файл,
+// поэтому, как и у HTTP-транспорта, размер не ограничивается. Принимаемые сообщения ограничены --max-decompressed-size
+const maxMsgSize = math.MaxInt32
+
+func msgSizeLimit(limit int64) int {
+	return int(min(limit, math.MaxInt))
+}
+
+// grpcHandler - реализация сервиса Grep поверх того же TaskProcessor, что и у HTTP-сервера
+type grpcHandler struct {
+	grpcpb.UnimplementedGrepServer
+	Proc   TaskProcessor
+	Limits Limits
+}
+
+// NewGRPCServer создает gRPC-сервер slave-ноды с зарегистрированным сервисом Grep
+func NewGRPCServer(p TaskProcessor, opts ServerOptions) *grpc.Server {
+	opts = opts.withDefaults()
+	srvOpts := []grpc.ServerOption{
+		// gRPC проверяет одним ограничением размер сообщения и до, и после распаковки
+		grpc.MaxRecvMsgSize(msgSizeLimit(min(opts.MaxDecompressedSize, opts.Limits.MaxBodySize))),
+		grpc.MaxSendMsgSize(maxMsgSize),
+		grpc.StatsHandler(metricsHandler{opts.Metrics}),
+	}
+	if opts.TLS != nil {
+		srvOpts = append(srvOpts, grpc.Creds(credentials.NewTLS(opts.TLS)))
+	}
+	if opts.Auth != nil {
+		srvOpts = append(srvOpts, authInterceptors(opts.Auth)...)
+	}
+	srv := grpc.NewServer(srvOpts...)
+	grpcpb.RegisterGrepServer(srv, &grpcHandler{Proc: p, Limits: opts.Limits})
+	return srv
+}
+
+func (gh *grpcHandler) Health(context.Context, *grpcpb.HealthRequest) (*grpcpb.HealthResponse, error) {
+	info := localNodeInfo()
+	return &grpcpb.HealthResponse{Protocol: uint32(info.Protocol), Version: info.Version, Features: info.Features}, nil
+}
+
+func (gh *grpcHandler) Task(ctx context.Context, req *grpcpb.TaskRequest) (*grpcpb.TaskResult, error) {
+	task, err := gh.slaveTask(req)
+	if err != nil {
+		return nil, err
+	}
+	return resultToProto(gh.Proc.ProcessInput(ctx, task)), nil
+}
+
+// TaskStream обрабатывает задания потока параллельно(не больше GOMAXPROCS одновременно)
+// и отправляет результаты по мере готовности. Сообщение с cancel отменяет задание: ожидающее
+// очереди не запускается, выполняемое прерывается, результат отмененного задания не отправляется.
+// Недопустимое задание не выполняется: вместо результата отправляется отказ(rejected), как 4xx по HTTP
+func (gh *grpcHandler) TaskStream(stream grpcpb.Grep_TaskStreamServer) error {
+	ctx := stream.Context()
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	wg := sync.WaitGroup{}
+	// Send нельзя вызывать из нескольких горутин одновременно; mu защищает и cancels - задания потока без результата
+	mu := sync.Mutex{}
+	cancels := make(map[string]context.CancelFunc)
+	var sendErr error
+	// задания занимают слоты по порядку поступления: задание ждет, пока слот займет(или будет отменено) предыдущее.
+	// Поток при этом читается дальше, чтобы отмены доходили и до заданий в очереди
+	turn := make(chan struct{})
+	close(turn)
+
+	defer wg.Wait()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetCancel() {
+			mu.Lock()
+			if cancel, ok := cancels[req.GetTid()]; ok {
+				cancel()
+			}
+			mu.Unlock()
+			continue
+		}
+		task, err := gh.slaveTask(req)
+		if err != nil {
+			// отказ в задании отправляется его результатом - остальные задания потока выполняются
+			var te *TaskError
+			if !errors.As(err, &te) {
+				return err
+			}
+			mu.Lock()
+			if sendErr == nil {
+				sendErr = stream.Send(&grpcpb.TaskResult{Tid: req.GetTid(), Rejected: rejectionToProto(te)})
+			}
+			mu.Unlock()
+			continue
+		}
+
+		tCtx, cancel := context.WithCancel(ctx)
+		mu.Lock()
+		cancels[task.TaskID] = cancel
+		mu.Unlock()
+		prev, next := turn, make(chan struct{})
+		turn = next
+		wg.Go(func() {
+			defer cancel()
+			acquired := false
+			select {
+			case <-prev:
+				select {
+				case sem <- struct{}{}:
+					acquired = true
+				case <-tCtx.Done():
+				}
+			case <-tCtx.Done():
+				<-prev
+			}
+			close(next)
+			if !acquired {
+				mu.Lock()
+				delete(cancels, task.TaskID)
+				mu.Unlock()
+				return
+			}
+			res := resultToProto(gh.Proc.ProcessInput(tCtx, task))
+			<-sem
+			mu.Lock()
+			defer mu.Unlock()
+			delete(cancels, task.TaskID)
+			if sendErr == nil && tCtx.Err() == nil {
+				sendErr = stream.Send(res)
+			}
+		})
+	}
+
+	wg.Wait()
+	return sendErr
+}
+
+// grpcServer приводит grpc.Server к интерфейсу Server
+type grpcServer struct {
+	addr string
+	srv  *grpc.Server
+}
+
+func (gs *grpcServer) ListenAndServe() error {
+	lis, err := net.Listen("tcp", gs.addr)
+	if err != nil {
+		return err
+	}
+	if err := gs.srv.Serve(lis); err != nil {
+		return err
+	}
+	return http.ErrServerClosed // Serve возвращает nil только после остановки сервера
+}
+
+// Shutdown дожидается завершения начатых заданий, а по истечении ctx обрывает их
+func (gs *grpcServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		gs.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		gs.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// grpcClient держит по одному соединению на slave-ноду; к нодам с адресом https:// подключается по TLS
+type grpcClient struct {
+	opts  ClientOptions
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+	nodes nodeFeatures
+}
+
+func newGRPCClient(opts ClientOptions) *grpcClient {
+	return &grpcClient{opts: opts, conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (gc *grpcClient) conn(addr string) (grpcpb.GrepClient, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if cc, ok := gc.conns[addr]; ok {
+		return grpcpb.NewGrepClient(cc), nil
+	}
+	creds := insecure.NewCredentials()
+	if IsTLSAddr(addr) {
+		creds = credentials.NewTLS(gc.opts.TLS)
+	}
+	callOpts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(msgSizeLimit(gc.opts.MaxDecompressedSize)), grpc.MaxCallSendMsgSize(maxMsgSize)}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(callOpts...),
+		grpc.WithStatsHandler(metricsHandler{gc.opts.Metrics}),
+	}
+	if gc.opts.Token != "" {
+		if !IsTLSAddr(addr) {
+			return nil, fmt.Errorf("%w: %q", errTokenWithoutTLS, addr)
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenCredentials(gc.opts.Token)))
+	}
+	cc, err := grpc.NewClient(HostPort(addr), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	gc.conns[addr] = cc
+	return grpcpb.NewGrepClient(cc), nil
+}
+
+func (gc *grpcClient) Ping(ctx context.Context, addr string) (*model.NodeInfo, error) {
+	c, err := gc.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Health(ctx, &grpcpb.HealthRequest{})
+	if err != nil {
+		return nil, err
+	}
+	info := &model.NodeInfo{
+		Protocol: int(resp.GetProtocol()),
+		Version:  resp.GetVersion(),
+		Features: resp.GetFeatures(),
+	}
+	gc.nodes.update(addr, info)
+	return legacyNodeInfo(info), nil
+}
+
+// compressor - сжатие заданий для ноды addr: без компрессора gzip старая нода отвечает Unimplemented
+func (gc *grpcClient) compressor(addr string) []grpc.CallOption {
+	if gc.opts.Compress && gc.nodes.has(addr, model.FeatureGzip) {
+		return []grpc.CallOption{grpc.UseCompressor(gzip.Name)} // ответ нода сожмет тем же алгоритмом
+	}
+	return nil
+}
+
+func (gc *grpcClient) SendTask(ctx context.Context, addr string, task *model.TaskDTO) (*model.SlaveResult, error) {
+	c, err := gc.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Task(ctx, taskToProto(task), gc.compressor(addr)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to SEND task: %w", err)
+	}
+	return resultFromProto(res), nil
+}
+
+// StreamTasks отправляет задания по одному потоку TaskStream и возвращается, когда у каждого задания есть
+// результат или отмена(задание CTX отменено), или поток оборвался. Ноде с model.FeatureStreamCancel отмена
+// передается сразу, остальные прекращают работу, когда поток закрывается
+func (gc *grpcClient) StreamTasks(ctx context.Context, addr string, tasks []*model.MasterTask, onResult func(*model.SlaveResult),
+	onReject func(taskID string, err error)) error {
+	c, err := gc.conn(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // закрывает поток, даже если нода еще выполняет отмененные задания
+	stream, err := c.TaskStream(ctx, gc.compressor(addr)...)
+	if err != nil {
+		return fmt.Errorf("failed to OPEN task stream: %w", err)
+	}
+
+	// отмены заданий приходят из context.AfterFunc - канал с запасом, чтобы они не блокировались
+	cancelled := make(chan string, len(tasks))
+	pending := make(map[string]bool, len(tasks)) // задания без результата и без отмены
+	for _, t := range tasks {
+		pending[t.Task.TaskID] = true
+		stop := context.AfterFunc(t.CTX, func() { cancelled <- t.Task.TaskID })
+		defer stop()
+	}
+
+	// задания отправляем в отдельной горутине, чтобы slave-нода могла возвращать результаты, не дожидаясь всех заданий;
+	// она же отправляет отмены - Send нельзя вызывать из нескольких горутин. Ошибку Send не возвращаем:
+	// настоящая причина придет из Recv
+	toCancel := make(chan string, len(tasks))
+	go func() {
+		for _, t := range tasks {
+			if t.CTX.Err() != nil {
+				continue // отменено до отправки
+			}
+			if err := stream.Send(taskToProto(&t.Task)); err != nil {
+				return
+			}
+		}
+		for {
+			select {
+			case tid := <-toCancel:
+				if err := stream.Send(&grpcpb.TaskRequest{Tid: tid, Cancel: true}); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan *grpcpb.TaskResult)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case results <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	canCancel := gc.nodes.has(addr, model.FeatureStreamCancel)
+	for len(pending) != 0 {
+		select {
+		case res := <-results:
+			if !pending[res.GetTid()] {
+				continue
+			}
+			delete(pending, res.GetTid())
+			if rej := res.GetRejected(); rej != nil {
+				onReject(res.GetTid(), rejectionFromProto(rej))
+				continue
+			}
+			onResult(resultFromProto(res))
+		case tid := <-cancelled:
+			if pending[tid] {
+				delete(pending, tid)
+				if canCancel {
+					toCancel <- tid
+				}
+			}
+		case err := <-recvErr:
+			return fmt.Errorf("failed to RECEIVE result: %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (gc *grpcClient) Close() error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	var errs []error
+	for addr, cc := range gc.conns {
+		errs = append(errs, cc.Close())
+		delete(gc.conns, addr)
+	}
+	return errors.Join(errs...)
+}
+
+// преобразования между model и сообщениями grpcpb
+
+func taskToProto(t *model.TaskDTO) *grpcpb.TaskRequest {
+	return &grpcpb.TaskRequest{
+		Tid:        t.TaskID,
+		GrepParam:  grepParamToProto(&t.GP),
+		Input:      toBytesList(t.Input),
+		FileName:   []byte(t.FileName),
+		Binary:     t.Binary,
+		Offsets:    t.Offsets,
+		BaseOffset: t.BaseOffset,
+		BaseLine:   int64(t.BaseLine),
+		Paths:      toBytesList(t.Paths),
+		Features:   t.Features,
+		Carry:      carryToProto(t.Carry),
+	}
+}
+
+// slaveTask переводит задание в model.SlaveTask и проверяет его так же, как ReceiveTask; ошибка - TaskError
+func (gh *grpcHandler) slaveTask(req *grpcpb.TaskRequest) (*model.SlaveTask, error) {
+	if req.GetGrepParam() == nil {
+		return nil, &TaskError{Status: http.StatusBadRequest, Message: "grep_param is required", Code: CodeMalformedTask, Field: "grep_param"}
+	}
+	task := taskFromProto(req)
+	if te := validateTask(task, gh.Limits); te != nil {
+		return nil, te
+	}
+	return task, nil
+}
+
+func taskFromProto(req *grpcpb.TaskRequest) *model.SlaveTask {
+	return &model.SlaveTask{
+		TaskID:     req.GetTid(),
+		GP:         grepParamFromProto(req.GetGrepParam()),
+		Input:      fromBytesList(req.GetInput()),
+		FileName:   string(req.GetFileName()),
+		Binary:     req.GetBinary(),
+		Offsets:    req.GetOffsets(),
+		BaseOffset: req.GetBaseOffset(),
+		BaseLine:   int(req.GetBaseLine()),
+		Paths:      fromBytesList(req.GetPaths()),
+		Features:   req.GetFeatures(),
+		Carry:      carryFromProto(req.GetCarry()),
+	}
+}
+
+// grepParamToProto переносит те же поля, что попадают в JSON задания
+func grepParamToProto(gp *model.GrepParam) *grpcpb.GrepParam {
+	pgp := &grpcpb.GrepParam{
+		CtxAfter:          int64(gp.CtxAfter),
+		CtxBefore:         int64(gp.CtxBefore),
+		CountFound:        gp.CountFound,
+		IgnoreCase:        gp.IgnoreCase,
+		InvertResult:      gp.InvertResult,
+		ExactMatch:        gp.ExactMatch,
+		EnumLine:          gp.EnumLine,
+		Pattern:           []byte(gp.Pattern),
+		PrintFilename:     gp.PrintFileName,
+		FilesWithMatch:    gp.FilesWithMatch,
+		FilesWithoutMatch: gp.FilesWithoutMatch,
+		Color:             gp.Color,
+		BinaryFiles:       gp.BinaryFiles,
+		NullData:          gp.NullData,
+		NullName:          gp.NullName,
+		ByteOffset:        gp.ByteOffset,
+		Column:            gp.Column,
+		Label:             []byte(gp.Label),
+		NoGroupSeparator:  gp.NoGroupSeparator,
+		Heading:           gp.Heading,
+		Decompress:        gp.Decompress,
+		MaxLineSize:       int64(gp.MaxLineSize),
+		LongLines:         gp.LongLines,
+		Encoding:          gp.Encoding,
+		Archives:          gp.Archives,
+		MaxMemberSize:     gp.MaxMemberSize,
+		MaxArchiveSize:    gp.MaxArchiveSize,
+		MaxArchiveMembers: int64(gp.MaxArchiveMembers),
+		Remote:            gp.Remote,
+	}
+	if gp.MaxCount != nil {
+		m := int64(*gp.MaxCount)
+		pgp.MaxCount = &m
+	}
+	if gp.GroupSeparator != nil {
+		pgp.GroupSeparator = append([]byte{}, *gp.GroupSeparator...) // пустой, но не nil: разделитель задан
+	}
+	return pgp
+}
+
+func grepParamFromProto(pgp *grpcpb.GrepParam) model.GrepParam {
+	gp := model.GrepParam{
+		CtxAfter:          int(pgp.GetCtxAfter()),
+		CtxBefore:         int(pgp.GetCtxBefore()),
+		CountFound:        pgp.GetCountFound(),
+		IgnoreCase:        pgp.GetIgnoreCase(),
+		InvertResult:      pgp.GetInvertResult(),
+		ExactMatch:        pgp.GetExactMatch(),
+		EnumLine:          pgp.GetEnumLine(),
+		Pattern:           string(pgp.GetPattern()),
+		PrintFileName:     pgp.GetPrintFilename(),
+		FilesWithMatch:    pgp.GetFilesWithMatch(),
+		FilesWithoutMatch: pgp.GetFilesWithoutMatch(),
+		Color:             pgp.GetColor(),
+		BinaryFiles:       pgp.GetBinaryFiles(),
+		NullData:          pgp.GetNullData(),
+		NullName:          pgp.GetNullName(),
+		ByteOffset:        pgp.GetByteOffset(),
+		Column:            pgp.GetColumn(),
+		Label:             string(pgp.GetLabel()),
+		NoGroupSeparator:  pgp.GetNoGroupSeparator(),
+		Heading:           pgp.GetHeading(),
+		Decompress:        pgp.GetDecompress(),
+		MaxLineSize:       int(pgp.GetMaxLineSize()),
+		LongLines:         pgp.GetLongLines(),
+		Encoding:          pgp.GetEncoding(),
+		Archives:          pgp.GetArchives(),
+		MaxMemberSize:     pgp.GetMaxMemberSize(),
+		MaxArchiveSize:    pgp.GetMaxArchiveSize(),
+		MaxArchiveMembers: int(pgp.GetMaxArchiveMembers()),
+		Remote:            pgp.GetRemote(),
+	}
+	if pgp.MaxCount != nil {
+		m := int(*pgp.MaxCount)
+		gp.MaxCount = &m
+	}
+	if pgp.GroupSeparator != nil {
+		sep := string(pgp.GroupSeparator)
+		gp.GroupSeparator = &sep
+	}
+	return gp
+}
+
+func carryToProto(c *model.ContextCarry) *grpcpb.ContextCarry {
+	if c == nil {
+		return nil
+	}
+	return &grpcpb.ContextCarry{Before: int64(c.Before), After: int64(c.After), LastPrinted: int64(c.LastPrinted)}
+}
+
+func carryFromProto(pc *grpcpb.ContextCarry) *model.ContextCarry {
+	if pc == nil {
+		return nil
+	}
+	return &model.ContextCarry{Before: int(pc.GetBefore()), After: int(pc.GetAfter()), LastPrinted: int(pc.GetLastPrinted())}
+}
+
+func rejectionToProto(te *TaskError) *grpcpb.TaskRejection {
+	return &grpcpb.TaskRejection{Status: int32(te.Status), Message: te.Message, Code: te.Code, Field: te.Field}
+}
+
+// rejectionFromProto возвращает отказ в задании потока в том же виде, что и отказ по HTTP в SendTask
+func rejectionFromProto(rej *grpcpb.TaskRejection) error {
+	te := &TaskError{Status: int(rej.GetStatus()), Message: rej.GetMessage(), Code: rej.GetCode(), Field: rej.GetField()}
+	return fmt.Errorf("task rejected with status %q: %w", fmt.Sprintf("%d %s", te.Status, http.StatusText(te.Status)), te)
+}
+
+func resultToProto(r *model.SlaveResult) *grpcpb.TaskResult {
+	res := &grpcpb.TaskResult{
+		Tid:      r.TaskID,
+		Hash:     r.HashSumm,
+		Output:   toBytesList(r.Output),
+		Selected: int64(r.Selected),
+		Matched:  int64(r.Matched),
+		Node:     r.Node,
+		Errors:   toBytesList(r.Errors),
+		Carry:    carryToProto(r.Carry),
+	}
+	for _, lm := range r.Meta {
+		plm := &grpcpb.LineMeta{K: uint32(lm.Kind), P: int64(lm.Prefix)}
+		for _, s := range lm.Spans {
+			plm.S = append(plm.S, &grpcpb.Span{B: int64(s.Start), E: int64(s.End)})
+		}
+		res.Meta = append(res.Meta, plm)
+	}
+	return res
+}
+
+func resultFromProto(res *grpcpb.TaskResult) *model.SlaveResult {
+	r := &model.SlaveResult{
+		TaskID:   res.GetTid(),
+		HashSumm: res.GetHash(),
+		Output:   fromBytesList(res.GetOutput()),
+		Selected: int(res.GetSelected()),
+		Matched:  int(res.GetMatched()),
+		Node:     res.GetNode(),
+		Errors:   fromBytesList(res.GetErrors()),
+		Carry:    carryFromProto(res.GetCarry()),
+	}
+	for _, plm := range res.GetMeta() {
+		lm := model.LineMeta{Kind: model.LineKind(plm.GetK()), Prefix: int(plm.GetP())}
+		for _, s := range plm.GetS() {
+			lm.Spans = append(lm.Spans, model.Span{Start: int(s.GetB()), End: int(s.GetE())})
+		}
+		r.Meta = append(r.Meta, lm)
+	}
+	return r
+}
+
+func toBytesList(ss []string) [][]byte {
+	if ss == nil {
+		return nil
+	}
+	res := make([][]byte, len(ss))
+	for i, s := range ss {
+		res[i] = []byte(s)
+	}
+	return res
+}
+
+// fromBytesList возвращает пустой, а не nil слайс для пустого списка - как JSON-транспорт для "input": []
+func fromBytesList(bs [][]byte) []string {
+	res := make([]string, len(bs))
+	for i, b := range bs {
+		res[i] = string(b)
+	}
+	return res
+}
@@ -0,0 +1,1074 @@
+// gRPC-транспорт между мастером и slave-нодами - альтернатива HTTP(POST /task, GET /ping).
+// Сообщения повторяют JSON-модель из internal/model; строки входа, вывода, имена файлов и шаблон
+// передаются как bytes: вход может быть двоичным, а proto3 string обязан быть валидным UTF-8.
+//
+// Генерация(нужны protoc, protoc-gen-go и protoc-gen-go-grpc): go generate ./internal/transport
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: grep.proto
+
+package grpcpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_grep_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{0}
+}
+
+// HealthResponse - то же, что JSON-ответ GET /ping(model.NodeInfo); у slave-нод без согласования
+// возможностей сообщение пустое
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Protocol      uint32                 `protobuf:"varint,1,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Features      []string               `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_grep_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HealthResponse) GetProtocol() uint32 {
+	if x != nil {
+		return x.Protocol
+	}
+	return 0
+}
+
+func (x *HealthResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+type GrepParam struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CtxAfter          int64                  `protobuf:"varint,1,opt,name=ctx_after,json=ctxAfter,proto3" json:"ctx_after,omitempty"`
+	CtxBefore         int64                  `protobuf:"varint,2,opt,name=ctx_before,json=ctxBefore,proto3" json:"ctx_before,omitempty"`
+	CountFound        bool                   `protobuf:"varint,3,opt,name=count_found,json=countFound,proto3" json:"count_found,omitempty"`
+	IgnoreCase        bool                   `protobuf:"varint,4,opt,name=ignore_case,json=ignoreCase,proto3" json:"ignore_case,omitempty"`
+	InvertResult      bool                   `protobuf:"varint,5,opt,name=invert_result,json=invertResult,proto3" json:"invert_result,omitempty"`
+	ExactMatch        bool                   `protobuf:"varint,6,opt,name=exact_match,json=exactMatch,proto3" json:"exact_match,omitempty"`
+	EnumLine          bool                   `protobuf:"varint,7,opt,name=enum_line,json=enumLine,proto3" json:"enum_line,omitempty"`
+	Pattern           []byte                 `protobuf:"bytes,8,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	PrintFilename     bool                   `protobuf:"varint,9,opt,name=print_filename,json=printFilename,proto3" json:"print_filename,omitempty"`
+	MaxCount          *int64                 `protobuf:"varint,10,opt,name=max_count,json=maxCount,proto3,oneof" json:"max_count,omitempty"`
+	FilesWithMatch    bool                   `protobuf:"varint,11,opt,name=files_with_match,json=filesWithMatch,proto3" json:"files_with_match,omitempty"`
+	FilesWithoutMatch bool                   `protobuf:"varint,12,opt,name=files_without_match,json=filesWithoutMatch,proto3" json:"files_without_match,omitempty"`
+	Color             bool                   `protobuf:"varint,13,opt,name=color,proto3" json:"color,omitempty"`
+	BinaryFiles       string                 `protobuf:"bytes,14,opt,name=binary_files,json=binaryFiles,proto3" json:"binary_files,omitempty"`
+	NullData          bool                   `protobuf:"varint,15,opt,name=null_data,json=nullData,proto3" json:"null_data,omitempty"`
+	NullName          bool                   `protobuf:"varint,16,opt,name=null_name,json=nullName,proto3" json:"null_name,omitempty"`
+	ByteOffset        bool                   `protobuf:"varint,17,opt,name=byte_offset,json=byteOffset,proto3" json:"byte_offset,omitempty"`
+	Column            bool                   `protobuf:"varint,18,opt,name=column,proto3" json:"column,omitempty"`
+	Label             []byte                 `protobuf:"bytes,19,opt,name=label,proto3" json:"label,omitempty"`
+	GroupSeparator    []byte                 `protobuf:"bytes,20,opt,name=group_separator,json=groupSeparator,proto3,oneof" json:"group_separator,omitempty"`
+	NoGroupSeparator  bool                   `protobuf:"varint,21,opt,name=no_group_separator,json=noGroupSeparator,proto3" json:"no_group_separator,omitempty"`
+	Heading           bool                   `protobuf:"varint,22,opt,name=heading,proto3" json:"heading,omitempty"`
+	Decompress        bool                   `protobuf:"varint,23,opt,name=decompress,proto3" json:"decompress,omitempty"`
+	MaxLineSize       int64                  `protobuf:"varint,24,opt,name=max_line_size,json=maxLineSize,proto3" json:"max_line_size,omitempty"`
+	LongLines         string                 `protobuf:"bytes,25,opt,name=long_lines,json=longLines,proto3" json:"long_lines,omitempty"`
+	Encoding          string                 `protobuf:"bytes,26,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	Archives          bool                   `protobuf:"varint,27,opt,name=archives,proto3" json:"archives,omitempty"`
+	MaxMemberSize     int64                  `protobuf:"varint,28,opt,name=max_member_size,json=maxMemberSize,proto3" json:"max_member_size,omitempty"`
+	Remote            bool                   `protobuf:"varint,29,opt,name=remote,proto3" json:"remote,omitempty"`
+	MaxArchiveSize    int64                  `protobuf:"varint,30,opt,name=max_archive_size,json=maxArchiveSize,proto3" json:"max_archive_size,omitempty"`
+	MaxArchiveMembers int64                  `protobuf:"varint,31,opt,name=max_archive_members,json=maxArchiveMembers,proto3" json:"max_archive_members,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GrepParam) Reset() {
+	*x = GrepParam{}
+	mi := &file_grep_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GrepParam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrepParam) ProtoMessage() {}
+
+func (x *GrepParam) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrepParam.ProtoReflect.Descriptor instead.
+func (*GrepParam) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GrepParam) GetCtxAfter() int64 {
+	if x != nil {
+		return x.CtxAfter
+	}
+	return 0
+}
+
+func (x *GrepParam) GetCtxBefore() int64 {
+	if x != nil {
+		return x.CtxBefore
+	}
+	return 0
+}
+
+func (x *GrepParam) GetCountFound() bool {
+	if x != nil {
+		return x.CountFound
+	}
+	return false
+}
+
+func (x *GrepParam) GetIgnoreCase() bool {
+	if x != nil {
+		return x.IgnoreCase
+	}
+	return false
+}
+
+func (x *GrepParam) GetInvertResult() bool {
+	if x != nil {
+		return x.InvertResult
+	}
+	return false
+}
+
+func (x *GrepParam) GetExactMatch() bool {
+	if x != nil {
+		return x.ExactMatch
+	}
+	return false
+}
+
+func (x *GrepParam) GetEnumLine() bool {
+	if x != nil {
+		return x.EnumLine
+	}
+	return false
+}
+
+func (x *GrepParam) GetPattern() []byte {
+	if x != nil {
+		return x.Pattern
+	}
+	return nil
+}
+
+func (x *GrepParam) GetPrintFilename() bool {
+	if x != nil {
+		return x.PrintFilename
+	}
+	return false
+}
+
+func (x *GrepParam) GetMaxCount() int64 {
+	if x != nil && x.MaxCount != nil {
+		return *x.MaxCount
+	}
+	return 0
+}
+
+func (x *GrepParam) GetFilesWithMatch() bool {
+	if x != nil {
+		return x.FilesWithMatch
+	}
+	return false
+}
+
+func (x *GrepParam) GetFilesWithoutMatch() bool {
+	if x != nil {
+		return x.FilesWithoutMatch
+	}
+	return false
+}
+
+func (x *GrepParam) GetColor() bool {
+	if x != nil {
+		return x.Color
+	}
+	return false
+}
+
+func (x *GrepParam) GetBinaryFiles() string {
+	if x != nil {
+		return x.BinaryFiles
+	}
+	return ""
+}
+
+func (x *GrepParam) GetNullData() bool {
+	if x != nil {
+		return x.NullData
+	}
+	return false
+}
+
+func (x *GrepParam) GetNullName() bool {
+	if x != nil {
+		return x.NullName
+	}
+	return false
+}
+
+func (x *GrepParam) GetByteOffset() bool {
+	if x != nil {
+		return x.ByteOffset
+	}
+	return false
+}
+
+func (x *GrepParam) GetColumn() bool {
+	if x != nil {
+		return x.Column
+	}
+	return false
+}
+
+func (x *GrepParam) GetLabel() []byte {
+	if x != nil {
+		return x.Label
+	}
+	return nil
+}
+
+func (x *GrepParam) GetGroupSeparator() []byte {
+	if x != nil {
+		return x.GroupSeparator
+	}
+	return nil
+}
+
+func (x *GrepParam) GetNoGroupSeparator() bool {
+	if x != nil {
+		return x.NoGroupSeparator
+	}
+	return false
+}
+
+func (x *GrepParam) GetHeading() bool {
+	if x != nil {
+		return x.Heading
+	}
+	return false
+}
+
+func (x *GrepParam) GetDecompress() bool {
+	if x != nil {
+		return x.Decompress
+	}
+	return false
+}
+
+func (x *GrepParam) GetMaxLineSize() int64 {
+	if x != nil {
+		return x.MaxLineSize
+	}
+	return 0
+}
+
+func (x *GrepParam) GetLongLines() string {
+	if x != nil {
+		return x.LongLines
+	}
+	return ""
+}
+
+func (x *GrepParam) GetEncoding() string {
+	if x != nil {
+		return x.Encoding
+	}
+	return ""
+}
+
+func (x *GrepParam) GetArchives() bool {
+	if x != nil {
+		return x.Archives
+	}
+	return false
+}
+
+func (x *GrepParam) GetMaxMemberSize() int64 {
+	if x != nil {
+		return x.MaxMemberSize
+	}
+	return 0
+}
+
+func (x *GrepParam) GetRemote() bool {
+	if x != nil {
+		return x.Remote
+	}
+	return false
+}
+
+func (x *GrepParam) GetMaxArchiveSize() int64 {
+	if x != nil {
+		return x.MaxArchiveSize
+	}
+	return 0
+}
+
+func (x *GrepParam) GetMaxArchiveMembers() int64 {
+	if x != nil {
+		return x.MaxArchiveMembers
+	}
+	return 0
+}
+
+type TaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tid           string                 `protobuf:"bytes,1,opt,name=tid,proto3" json:"tid,omitempty"`
+	GrepParam     *GrepParam             `protobuf:"bytes,2,opt,name=grep_param,json=grepParam,proto3" json:"grep_param,omitempty"`
+	Input         [][]byte               `protobuf:"bytes,3,rep,name=input,proto3" json:"input,omitempty"`
+	FileName      []byte                 `protobuf:"bytes,4,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	Binary        bool                   `protobuf:"varint,5,opt,name=binary,proto3" json:"binary,omitempty"`
+	Offsets       []int64                `protobuf:"varint,6,rep,packed,name=offsets,proto3" json:"offsets,omitempty"`
+	BaseOffset    int64                  `protobuf:"varint,7,opt,name=base_offset,json=baseOffset,proto3" json:"base_offset,omitempty"`
+	BaseLine      int64                  `protobuf:"varint,8,opt,name=base_line,json=baseLine,proto3" json:"base_line,omitempty"`
+	Paths         [][]byte               `protobuf:"bytes,9,rep,name=paths,proto3" json:"paths,omitempty"`
+	Features      []string               `protobuf:"bytes,10,rep,name=features,proto3" json:"features,omitempty"`
+	Cancel        bool                   `protobuf:"varint,11,opt,name=cancel,proto3" json:"cancel,omitempty"` // только в TaskStream: отмена задания tid, остальные поля не заполняются
+	Carry         *ContextCarry          `protobuf:"bytes,12,opt,name=carry,proto3" json:"carry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskRequest) Reset() {
+	*x = TaskRequest{}
+	mi := &file_grep_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskRequest) ProtoMessage() {}
+
+func (x *TaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskRequest.ProtoReflect.Descriptor instead.
+func (*TaskRequest) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TaskRequest) GetTid() string {
+	if x != nil {
+		return x.Tid
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetGrepParam() *GrepParam {
+	if x != nil {
+		return x.GrepParam
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetInput() [][]byte {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetFileName() []byte {
+	if x != nil {
+		return x.FileName
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetBinary() bool {
+	if x != nil {
+		return x.Binary
+	}
+	return false
+}
+
+func (x *TaskRequest) GetOffsets() []int64 {
+	if x != nil {
+		return x.Offsets
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetBaseOffset() int64 {
+	if x != nil {
+		return x.BaseOffset
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetBaseLine() int64 {
+	if x != nil {
+		return x.BaseLine
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetPaths() [][]byte {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
+func (x *TaskRequest) GetCarry() *ContextCarry {
+	if x != nil {
+		return x.Carry
+	}
+	return nil
+}
+
+type ContextCarry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Before        int64                  `protobuf:"varint,1,opt,name=before,proto3" json:"before,omitempty"`
+	After         int64                  `protobuf:"varint,2,opt,name=after,proto3" json:"after,omitempty"`
+	LastPrinted   int64                  `protobuf:"varint,3,opt,name=last_printed,json=lastPrinted,proto3" json:"last_printed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContextCarry) Reset() {
+	*x = ContextCarry{}
+	mi := &file_grep_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContextCarry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContextCarry) ProtoMessage() {}
+
+func (x *ContextCarry) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContextCarry.ProtoReflect.Descriptor instead.
+func (*ContextCarry) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ContextCarry) GetBefore() int64 {
+	if x != nil {
+		return x.Before
+	}
+	return 0
+}
+
+func (x *ContextCarry) GetAfter() int64 {
+	if x != nil {
+		return x.After
+	}
+	return 0
+}
+
+func (x *ContextCarry) GetLastPrinted() int64 {
+	if x != nil {
+		return x.LastPrinted
+	}
+	return 0
+}
+
+type Span struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	B             int64                  `protobuf:"varint,1,opt,name=b,proto3" json:"b,omitempty"`
+	E             int64                  `protobuf:"varint,2,opt,name=e,proto3" json:"e,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Span) Reset() {
+	*x = Span{}
+	mi := &file_grep_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Span) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Span) ProtoMessage() {}
+
+func (x *Span) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Span.ProtoReflect.Descriptor instead.
+func (*Span) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Span) GetB() int64 {
+	if x != nil {
+		return x.B
+	}
+	return 0
+}
+
+func (x *Span) GetE() int64 {
+	if x != nil {
+		return x.E
+	}
+	return 0
+}
+
+type LineMeta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	K             uint32                 `protobuf:"varint,1,opt,name=k,proto3" json:"k,omitempty"`
+	P             int64                  `protobuf:"varint,2,opt,name=p,proto3" json:"p,omitempty"`
+	S             []*Span                `protobuf:"bytes,3,rep,name=s,proto3" json:"s,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LineMeta) Reset() {
+	*x = LineMeta{}
+	mi := &file_grep_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LineMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LineMeta) ProtoMessage() {}
+
+func (x *LineMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LineMeta.ProtoReflect.Descriptor instead.
+func (*LineMeta) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LineMeta) GetK() uint32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+func (x *LineMeta) GetP() int64 {
+	if x != nil {
+		return x.P
+	}
+	return 0
+}
+
+func (x *LineMeta) GetS() []*Span {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+type TaskResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tid           string                 `protobuf:"bytes,1,opt,name=tid,proto3" json:"tid,omitempty"`
+	Hash          uint64                 `protobuf:"varint,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Output        [][]byte               `protobuf:"bytes,3,rep,name=output,proto3" json:"output,omitempty"`
+	Selected      int64                  `protobuf:"varint,4,opt,name=selected,proto3" json:"selected,omitempty"`
+	Meta          []*LineMeta            `protobuf:"bytes,5,rep,name=meta,proto3" json:"meta,omitempty"`
+	Node          string                 `protobuf:"bytes,6,opt,name=node,proto3" json:"node,omitempty"`
+	Errors        [][]byte               `protobuf:"bytes,7,rep,name=errors,proto3" json:"errors,omitempty"`
+	Matched       int64                  `protobuf:"varint,8,opt,name=matched,proto3" json:"matched,omitempty"`
+	Rejected      *TaskRejection         `protobuf:"bytes,9,opt,name=rejected,proto3" json:"rejected,omitempty"` // только в TaskStream: нода отказалась от задания tid, остальные поля не заполняются
+	Carry         *ContextCarry          `protobuf:"bytes,10,opt,name=carry,proto3" json:"carry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskResult) Reset() {
+	*x = TaskResult{}
+	mi := &file_grep_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskResult) ProtoMessage() {}
+
+func (x *TaskResult) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskResult.ProtoReflect.Descriptor instead.
+func (*TaskResult) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TaskResult) GetTid() string {
+	if x != nil {
+		return x.Tid
+	}
+	return ""
+}
+
+func (x *TaskResult) GetHash() uint64 {
+	if x != nil {
+		return x.Hash
+	}
+	return 0
+}
+
+func (x *TaskResult) GetOutput() [][]byte {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *TaskResult) GetSelected() int64 {
+	if x != nil {
+		return x.Selected
+	}
+	return 0
+}
+
+func (x *TaskResult) GetMeta() []*LineMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *TaskResult) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *TaskResult) GetErrors() [][]byte {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *TaskResult) GetMatched() int64 {
+	if x != nil {
+		return x.Matched
+	}
+	return 0
+}
+
+func (x *TaskResult) GetRejected() *TaskRejection {
+	if x != nil {
+		return x.Rejected
+	}
+	return nil
+}
+
+func (x *TaskResult) GetCarry() *ContextCarry {
+	if x != nil {
+		return x.Carry
+	}
+	return nil
+}
+
+// TaskRejection - TaskError задания потока: отказ в одном задании не обрывает TaskStream
+type TaskRejection struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	Field         string                 `protobuf:"bytes,4,opt,name=field,proto3" json:"field,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskRejection) Reset() {
+	*x = TaskRejection{}
+	mi := &file_grep_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskRejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskRejection) ProtoMessage() {}
+
+func (x *TaskRejection) ProtoReflect() protoreflect.Message {
+	mi := &file_grep_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskRejection.ProtoReflect.Descriptor instead.
+func (*TaskRejection) Descriptor() ([]byte, []int) {
+	return file_grep_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TaskRejection) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *TaskRejection) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *TaskRejection) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *TaskRejection) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+var File_grep_proto protoreflect.FileDescriptor
+
+const file_grep_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"grep.proto\x12\tmygrep.v1\"\x0f\n" +
+	"\rHealthRequest\"b\n" +
+	"\x0eHealthResponse\x12\x1a\n" +
+	"\bprotocol\x18\x01 \x01(\rR\bprotocol\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\"\xb8\b\n" +
+	"\tGrepParam\x12\x1b\n" +
+	"\tctx_after\x18\x01 \x01(\x03R\bctxAfter\x12\x1d\n" +
+	"\n" +
+	"ctx_before\x18\x02 \x01(\x03R\tctxBefore\x12\x1f\n" +
+	"\vcount_found\x18\x03 \x01(\bR\n" +
+	"countFound\x12\x1f\n" +
+	"\vignore_case\x18\x04 \x01(\bR\n" +
+	"ignoreCase\x12#\n" +
+	"\rinvert_result\x18\x05 \x01(\bR\finvertResult\x12\x1f\n" +
+	"\vexact_match\x18\x06 \x01(\bR\n" +
+	"exactMatch\x12\x1b\n" +
+	"\tenum_line\x18\a \x01(\bR\benumLine\x12\x18\n" +
+	"\apattern\x18\b \x01(\fR\apattern\x12%\n" +
+	"\x0eprint_filename\x18\t \x01(\bR\rprintFilename\x12 \n" +
+	"\tmax_count\x18\n" +
+	" \x01(\x03H\x00R\bmaxCount\x88\x01\x01\x12(\n" +
+	"\x10files_with_match\x18\v \x01(\bR\x0efilesWithMatch\x12.\n" +
+	"\x13files_without_match\x18\f \x01(\bR\x11filesWithoutMatch\x12\x14\n" +
+	"\x05color\x18\r \x01(\bR\x05color\x12!\n" +
+	"\fbinary_files\x18\x0e \x01(\tR\vbinaryFiles\x12\x1b\n" +
+	"\tnull_data\x18\x0f \x01(\bR\bnullData\x12\x1b\n" +
+	"\tnull_name\x18\x10 \x01(\bR\bnullName\x12\x1f\n" +
+	"\vbyte_offset\x18\x11 \x01(\bR\n" +
+	"byteOffset\x12\x16\n" +
+	"\x06column\x18\x12 \x01(\bR\x06column\x12\x14\n" +
+	"\x05label\x18\x13 \x01(\fR\x05label\x12,\n" +
+	"\x0fgroup_separator\x18\x14 \x01(\fH\x01R\x0egroupSeparator\x88\x01\x01\x12,\n" +
+	"\x12no_group_separator\x18\x15 \x01(\bR\x10noGroupSeparator\x12\x18\n" +
+	"\aheading\x18\x16 \x01(\bR\aheading\x12\x1e\n" +
+	"\n" +
+	"decompress\x18\x17 \x01(\bR\n" +
+	"decompress\x12\"\n" +
+	"\rmax_line_size\x18\x18 \x01(\x03R\vmaxLineSize\x12\x1d\n" +
+	"\n" +
+	"long_lines\x18\x19 \x01(\tR\tlongLines\x12\x1a\n" +
+	"\bencoding\x18\x1a \x01(\tR\bencoding\x12\x1a\n" +
+	"\barchives\x18\x1b \x01(\bR\barchives\x12&\n" +
+	"\x0fmax_member_size\x18\x1c \x01(\x03R\rmaxMemberSize\x12\x16\n" +
+	"\x06remote\x18\x1d \x01(\bR\x06remote\x12(\n" +
+	"\x10max_archive_size\x18\x1e \x01(\x03R\x0emaxArchiveSize\x12.\n" +
+	"\x13max_archive_members\x18\x1f \x01(\x03R\x11maxArchiveMembersB\f\n" +
+	"\n" +
+	"_max_countB\x12\n" +
+	"\x10_group_separator\"\xf0\x02\n" +
+	"\vTaskRequest\x12\x10\n" +
+	"\x03tid\x18\x01 \x01(\tR\x03tid\x123\n" +
+	"\n" +
+	"grep_param\x18\x02 \x01(\v2\x14.mygrep.v1.GrepParamR\tgrepParam\x12\x14\n" +
+	"\x05input\x18\x03 \x03(\fR\x05input\x12\x1b\n" +
+	"\tfile_name\x18\x04 \x01(\fR\bfileName\x12\x16\n" +
+	"\x06binary\x18\x05 \x01(\bR\x06binary\x12\x18\n" +
+	"\aoffsets\x18\x06 \x03(\x03R\aoffsets\x12\x1f\n" +
+	"\vbase_offset\x18\a \x01(\x03R\n" +
+	"baseOffset\x12\x1b\n" +
+	"\tbase_line\x18\b \x01(\x03R\bbaseLine\x12\x14\n" +
+	"\x05paths\x18\t \x03(\fR\x05paths\x12\x1a\n" +
+	"\bfeatures\x18\n" +
+	" \x03(\tR\bfeatures\x12\x16\n" +
+	"\x06cancel\x18\v \x01(\bR\x06cancel\x12-\n" +
+	"\x05carry\x18\f \x01(\v2\x17.mygrep.v1.ContextCarryR\x05carry\"_\n" +
+	"\fContextCarry\x12\x16\n" +
+	"\x06before\x18\x01 \x01(\x03R\x06before\x12\x14\n" +
+	"\x05after\x18\x02 \x01(\x03R\x05after\x12!\n" +
+	"\flast_printed\x18\x03 \x01(\x03R\vlastPrinted\"\"\n" +
+	"\x04Span\x12\f\n" +
+	"\x01b\x18\x01 \x01(\x03R\x01b\x12\f\n" +
+	"\x01e\x18\x02 \x01(\x03R\x01e\"E\n" +
+	"\bLineMeta\x12\f\n" +
+	"\x01k\x18\x01 \x01(\rR\x01k\x12\f\n" +
+	"\x01p\x18\x02 \x01(\x03R\x01p\x12\x1d\n" +
+	"\x01s\x18\x03 \x03(\v2\x0f.mygrep.v1.SpanR\x01s\"\xba\x02\n" +
+	"\n" +
+	"TaskResult\x12\x10\n" +
+	"\x03tid\x18\x01 \x01(\tR\x03tid\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\x04R\x04hash\x12\x16\n" +
+	"\x06output\x18\x03 \x03(\fR\x06output\x12\x1a\n" +
+	"\bselected\x18\x04 \x01(\x03R\bselected\x12'\n" +
+	"\x04meta\x18\x05 \x03(\v2\x13.mygrep.v1.LineMetaR\x04meta\x12\x12\n" +
+	"\x04node\x18\x06 \x01(\tR\x04node\x12\x16\n" +
+	"\x06errors\x18\a \x03(\fR\x06errors\x12\x18\n" +
+	"\amatched\x18\b \x01(\x03R\amatched\x124\n" +
+	"\brejected\x18\t \x01(\v2\x18.mygrep.v1.TaskRejectionR\brejected\x12-\n" +
+	"\x05carry\x18\n" +
+	" \x01(\v2\x17.mygrep.v1.ContextCarryR\x05carry\"k\n" +
+	"\rTaskRejection\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\x05R\x06status\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x14\n" +
+	"\x05field\x18\x04 \x01(\tR\x05field2\xbd\x01\n" +
+	"\x04Grep\x12=\n" +
+	"\x06Health\x12\x18.mygrep.v1.HealthRequest\x1a\x19.mygrep.v1.HealthResponse\x125\n" +
+	"\x04Task\x12\x16.mygrep.v1.TaskRequest\x1a\x15.mygrep.v1.TaskResult\x12?\n" +
+	"\n" +
+	"TaskStream\x12\x16.mygrep.v1.TaskRequest\x1a\x15.mygrep.v1.TaskResult(\x010\x01BHZFgithub.com/UnendingLoop/DistributedGrepClone/internal/transport/grpcpbb\x06proto3"
+
+var (
+	file_grep_proto_rawDescOnce sync.Once
+	file_grep_proto_rawDescData []byte
+)
+
+func file_grep_proto_rawDescGZIP() []byte {
+	file_grep_proto_rawDescOnce.Do(func() {
+		file_grep_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_grep_proto_rawDesc), len(file_grep_proto_rawDesc)))
+	})
+	return file_grep_proto_rawDescData
+}
+
+var file_grep_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_grep_proto_goTypes = []any{
+	(*HealthRequest)(nil),  // 0: mygrep.v1.HealthRequest
+	(*HealthResponse)(nil), // 1: mygrep.v1.HealthResponse
+	(*GrepParam)(nil),      // 2: mygrep.v1.GrepParam
+	(*TaskRequest)(nil),    // 3: mygrep.v1.TaskRequest
+	(*ContextCarry)(nil),   // 4: mygrep.v1.ContextCarry
+	(*Span)(nil),           // 5: mygrep.v1.Span
+	(*LineMeta)(nil),       // 6: mygrep.v1.LineMeta
+	(*TaskResult)(nil),     // 7: mygrep.v1.TaskResult
+	(*TaskRejection)(nil),  // 8: mygrep.v1.TaskRejection
+}
+var file_grep_proto_depIdxs = []int32{
+	2, // 0: mygrep.v1.TaskRequest.grep_param:type_name -> mygrep.v1.GrepParam
+	4, // 1: mygrep.v1.TaskRequest.carry:type_name -> mygrep.v1.ContextCarry
+	5, // 2: mygrep.v1.LineMeta.s:type_name -> mygrep.v1.Span
+	6, // 3: mygrep.v1.TaskResult.meta:type_name -> mygrep.v1.LineMeta
+	8, // 4: mygrep.v1.TaskResult.rejected:type_name -> mygrep.v1.TaskRejection
+	4, // 5: mygrep.v1.TaskResult.carry:type_name -> mygrep.v1.ContextCarry
+	0, // 6: mygrep.v1.Grep.Health:input_type -> mygrep.v1.HealthRequest
+	3, // 7: mygrep.v1.Grep.Task:input_type -> mygrep.v1.TaskRequest
+	3, // 8: mygrep.v1.Grep.TaskStream:input_type -> mygrep.v1.TaskRequest
+	1, // 9: mygrep.v1.Grep.Health:output_type -> mygrep.v1.HealthResponse
+	7, // 10: mygrep.v1.Grep.Task:output_type -> mygrep.v1.TaskResult
+	7, // 11: mygrep.v1.Grep.TaskStream:output_type -> mygrep.v1.TaskResult
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_grep_proto_init() }
+func file_grep_proto_init() {
+	if File_grep_proto != nil {
+		return
+	}
+	file_grep_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_grep_proto_rawDesc), len(file_grep_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_grep_proto_goTypes,
+		DependencyIndexes: file_grep_proto_depIdxs,
+		MessageInfos:      file_grep_proto_msgTypes,
+	}.Build()
+	File_grep_proto = out.File
+	file_grep_proto_goTypes = nil
+	file_grep_proto_depIdxs = nil
+}
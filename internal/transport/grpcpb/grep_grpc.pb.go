@@ -0,0 +1,206 @@
+// gRPC-транспорт между мастером и slave-нодами - альтернатива HTTP(POST /task, GET /ping).
+// Сообщения повторяют JSON-модель из internal/model; строки входа, вывода, имена файлов и шаблон
+// передаются как bytes: вход может быть двоичным, а proto3 string обязан быть валидным UTF-8.
+//
+// Генерация(нужны protoc, protoc-gen-go и protoc-gen-go-grpc): go generate ./internal/transport
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: grep.proto
+
+package grpcpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Grep_Health_FullMethodName     = "/mygrep.v1.Grep/Health"
+	Grep_Task_FullMethodName       = "/mygrep.v1.Grep/Task"
+	Grep_TaskStream_FullMethodName = "/mygrep.v1.Grep/TaskStream"
+)
+
+// GrepClient is the client API for Grep service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GrepClient interface {
+	// Health - аналог GET /ping
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Task - одно задание, аналог POST /task
+	Task(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*TaskResult, error)
+	// TaskStream - много заданий по одному потоку; результаты приходят по мере готовности, не по порядку.
+	// TaskRequest с cancel отменяет задание tid этого потока(возможность "stream-cancel"), его результат не отправляется
+	TaskStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TaskRequest, TaskResult], error)
+}
+
+type grepClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGrepClient(cc grpc.ClientConnInterface) GrepClient {
+	return &grepClient{cc}
+}
+
+func (c *grepClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, Grep_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grepClient) Task(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*TaskResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskResult)
+	err := c.cc.Invoke(ctx, Grep_Task_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grepClient) TaskStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TaskRequest, TaskResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Grep_ServiceDesc.Streams[0], Grep_TaskStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TaskRequest, TaskResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Grep_TaskStreamClient = grpc.BidiStreamingClient[TaskRequest, TaskResult]
+
+// GrepServer is the server API for Grep service.
+// All implementations must embed UnimplementedGrepServer
+// for forward compatibility.
+type GrepServer interface {
+	// Health - аналог GET /ping
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Task - одно задание, аналог POST /task
+	Task(context.Context, *TaskRequest) (*TaskResult, error)
+	// TaskStream - много заданий по одному потоку; результаты приходят по мере готовности, не по порядку.
+	// TaskRequest с cancel отменяет задание tid этого потока(возможность "stream-cancel"), его результат не отправляется
+	TaskStream(grpc.BidiStreamingServer[TaskRequest, TaskResult]) error
+	mustEmbedUnimplementedGrepServer()
+}
+
+// UnimplementedGrepServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGrepServer struct{}
+
+func (UnimplementedGrepServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedGrepServer) Task(context.Context, *TaskRequest) (*TaskResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method Task not implemented")
+}
+func (UnimplementedGrepServer) TaskStream(grpc.BidiStreamingServer[TaskRequest, TaskResult]) error {
+	return status.Error(codes.Unimplemented, "method TaskStream not implemented")
+}
+func (UnimplementedGrepServer) mustEmbedUnimplementedGrepServer() {}
+func (UnimplementedGrepServer) testEmbeddedByValue()              {}
+
+// UnsafeGrepServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GrepServer will
+// result in compilation errors.
+type UnsafeGrepServer interface {
+	mustEmbedUnimplementedGrepServer()
+}
+
+func RegisterGrepServer(s grpc.ServiceRegistrar, srv GrepServer) {
+	// If the following call panics, it indicates UnimplementedGrepServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Grep_ServiceDesc, srv)
+}
+
+func _Grep_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrepServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Grep_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrepServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Grep_Task_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrepServer).Task(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Grep_Task_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrepServer).Task(ctx, req.(*TaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Grep_TaskStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GrepServer).TaskStream(&grpc.GenericServerStream[TaskRequest, TaskResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Grep_TaskStreamServer = grpc.BidiStreamingServer[TaskRequest, TaskResult]
+
+// Grep_ServiceDesc is the grpc.ServiceDesc for Grep service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Grep_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mygrep.v1.Grep",
+	HandlerType: (*GrepServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Health",
+			Handler:    _Grep_Health_Handler,
+		},
+		{
+			MethodName: "Task",
+			Handler:    _Grep_Task_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TaskStream",
+			Handler:       _Grep_TaskStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "grep.proto",
+}
@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+)
+
+// схемы адресов slave-нод: по https:// мастер подключается по TLS, без схемы или по http:// - без шифрования
+const (
+	schemeHTTP  = "http://"
+	schemeHTTPS = "https://"
+)
+
+// IsTLSAddr сообщает, что адрес slave-ноды указан со схемой https://
+func IsTLSAddr(addr string) bool {
+	return strings.HasPrefix(addr, schemeHTTPS)
+}
+
+// HostPort - адрес slave-ноды без схемы
+func HostPort(addr string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(addr, schemeHTTPS), schemeHTTP)
+}
+
+// ServerTLSConfig собирает TLS-конфигурацию slave-ноды; без --tls-cert возвращает nil - нода работает без TLS.
+// Если задан CAFile, нода принимает только клиентов с сертификатом, подписанным этим CA(mTLS)
+func ServerTLSConfig(tp model.TLSParam) (*tls.Config, error) {
+	if tp.CertFile == "" && tp.KeyFile == "" {
+		if tp.CAFile != "" {
+			return nil, errors.New("--tls-ca on a slave-node requires --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(tp.CertFile, tp.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	conf := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if tp.CAFile != "" {
+		pool, err := loadCertPool(tp.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
+
+// ClientTLSConfig собирает TLS-конфигурацию мастера для нод с адресом https://. Если задан CAFile, сертификаты
+// slave-нод проверяются только по нему(pinning), иначе - по системным корневым сертификатам
+func ClientTLSConfig(tp model.TLSParam) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tp.CertFile != "" || tp.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tp.CertFile, tp.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if tp.CAFile != "" {
+		pool, err := loadCertPool(tp.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("no PEM certificates found in %q", caFile)
+	}
+	return pool, nil
+}
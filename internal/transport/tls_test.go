@@ -0,0 +1,203 @@
+package transport_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLS(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cases := []struct {
+		name      string
+		serverTLS model.TLSParam
+		clientTLS model.TLSParam
+		plainAddr bool // мастер обращается к ноде без https://
+		wantErr   bool
+	}{
+		{
+			name:      "Positive - TLS with pinned CA",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey},
+			clientTLS: model.TLSParam{CAFile: pki.ca},
+		},
+		{
+			name:      "Negative - slave certificate is not signed by a system root",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey},
+			clientTLS: model.TLSParam{},
+			wantErr:   true,
+		},
+		{
+			name:      "Negative - pinned CA doesn't match the slave certificate",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey},
+			clientTLS: model.TLSParam{CAFile: pki.otherCA},
+			wantErr:   true,
+		},
+		{
+			name:      "Negative - plaintext master to a TLS slave",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey},
+			plainAddr: true,
+			wantErr:   true,
+		},
+		{
+			name:      "Positive - mTLS with a client certificate signed by the CA",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey, CAFile: pki.ca},
+			clientTLS: model.TLSParam{CertFile: pki.clientCert, KeyFile: pki.clientKey, CAFile: pki.ca},
+		},
+		{
+			name:      "Negative - mTLS without a client certificate",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey, CAFile: pki.ca},
+			clientTLS: model.TLSParam{CAFile: pki.ca},
+			wantErr:   true,
+		},
+		{
+			name:      "Negative - mTLS with a client certificate signed by another CA",
+			serverTLS: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey, CAFile: pki.ca},
+			clientTLS: model.TLSParam{CertFile: pki.otherClientCert, KeyFile: pki.otherClientKey, CAFile: pki.ca},
+			wantErr:   true,
+		},
+	}
+
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		for _, tt := range cases {
+			t.Run(kind+" "+tt.name, func(t *testing.T) {
+				serverConf, err := transport.ServerTLSConfig(tt.serverTLS)
+				require.NoError(t, err)
+				addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{TLS: serverConf})
+				if tt.plainAddr {
+					addr = transport.HostPort(addr)
+				}
+
+				clientConf, err := transport.ClientTLSConfig(tt.clientTLS)
+				require.NoError(t, err)
+				client, err := transport.NewClient(kind, transport.ClientOptions{TLS: clientConf})
+				require.NoError(t, err)
+				defer client.Close()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_, err = client.Ping(ctx, addr)
+				if tt.wantErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+
+				res, err := client.SendTask(ctx, addr, &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"secret"}})
+				require.NoError(t, err)
+				require.Equal(t, []string{"secret", "", "p"}, res.Output)
+			})
+		}
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	pki := newTestPKI(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o644))
+
+	cases := []struct {
+		name   string
+		server bool
+		tp     model.TLSParam
+	}{
+		{name: "Negative - slave key doesn't exist", server: true, tp: model.TLSParam{CertFile: pki.serverCert, KeyFile: "/unreal.pem"}},
+		{name: "Negative - slave client CA without own certificate", server: true, tp: model.TLSParam{CAFile: pki.ca}},
+		{name: "Negative - slave client CA is not PEM", server: true, tp: model.TLSParam{CertFile: pki.serverCert, KeyFile: pki.serverKey, CAFile: notPEM}},
+		{name: "Negative - master CA is not PEM", tp: model.TLSParam{CAFile: notPEM}},
+		{name: "Negative - master key doesn't match certificate", tp: model.TLSParam{CertFile: pki.clientCert, KeyFile: pki.serverKey}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.server {
+				_, err = transport.ServerTLSConfig(tt.tp)
+			} else {
+				_, err = transport.ClientTLSConfig(tt.tp)
+			}
+			require.Error(t, err)
+		})
+	}
+
+	conf, err := transport.ServerTLSConfig(model.TLSParam{})
+	require.NoError(t, err)
+	require.Nil(t, conf, "slave-node without certificate must serve plaintext")
+}
+
+// testPKI - пути к PEM-файлам: CA, сертификат slave-ноды(127.0.0.1, localhost), клиентский сертификат мастера
+// от того же CA и клиентский сертификат от другого CA
+type testPKI struct {
+	ca, otherCA                     string
+	serverCert, serverKey           string
+	clientCert, clientKey           string
+	otherClientCert, otherClientKey string
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	var pki testPKI
+
+	caCert, caKey := issueCert(t, dir, "ca", nil, nil, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCertSign})
+	pki.ca = filepath.Join(dir, "ca.pem")
+	otherCert, otherKey := issueCert(t, dir, "other-ca", nil, nil, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCertSign})
+	pki.otherCA = filepath.Join(dir, "other-ca.pem")
+
+	issueCert(t, dir, "server", caCert, caKey, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	pki.serverCert, pki.serverKey = filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+
+	issueCert(t, dir, "client", caCert, caKey, &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	pki.clientCert, pki.clientKey = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+
+	issueCert(t, dir, "other-client", otherCert, otherKey, &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	pki.otherClientCert, pki.otherClientKey = filepath.Join(dir, "other-client.pem"), filepath.Join(dir, "other-client-key.pem")
+	return pki
+}
+
+// issueCert выпускает сертификат по шаблону tmpl, подписанный parent(без parent - самоподписанный),
+// и сохраняет его в dir/name.pem, а ключ - в dir/name-key.pem
+func issueCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	tmpl *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage |= x509.KeyUsageDigitalSignature
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return cert, key
+}
@@ -1,31 +1,102 @@
-// Package transport provides a new server-entity(by ginext) for slave-mode operability with handlers to serve endpoints
+// Package transport provides the slave-mode servers(HTTP by ginext or gRPC) serving tasks with a shared TaskProcessor,
+// and the master-side clients for both protocols
 package transport
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net/http"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
-	"github.com/gin-gonic/gin"
 	"github.com/wb-go/wbf/ginext"
 )
 
 type grepHandler struct {
-	Proc TaskProcessor
+	Proc    TaskProcessor
+	Metrics *Metrics
+	Limits  Limits
 }
 
+// TaskProcessor - общее ядро slave-ноды, не зависящее от транспорта
 type TaskProcessor interface {
 	ProcessInput(ctx context.Context, task *model.SlaveTask) *model.SlaveResult
 }
 
-func NewSlaveServer(addr string, p TaskProcessor) *http.Server {
+// Server - сервер slave-ноды; ListenAndServe после Shutdown возвращает http.ErrServerClosed для любого транспорта
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+// ServerOptions - настройки сервера slave-ноды, общие для обоих транспортов
+type ServerOptions struct {
+	TLS                 *tls.Config // см. ServerTLSConfig; nil - без TLS
+	Auth                *TokenStore // nil - без проверки токенов
+	MaxDecompressedSize int64       // ограничение задания после распаковки; 0 - DefaultMaxDecompressedSize
+	Metrics             *Metrics    // счетчики сжатых и несжатых байт; nil - не нужны снаружи
+	Limits              Limits      // ограничения размера заданий
+}
+
+func (opts ServerOptions) withDefaults() ServerOptions {
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &Metrics{}
+	}
+	opts.Limits = opts.Limits.withDefaults()
+	return opts
+}
+
+// NewServer создает сервер slave-ноды для транспорта kind(model.TransportHTTP или model.TransportGRPC)
+func NewServer(kind, addr string, p TaskProcessor, opts ServerOptions) (Server, error) {
+	switch kind {
+	case model.TransportHTTP, "":
+		srv := NewSlaveServer(addr, p, opts)
+		if opts.TLS == nil {
+			return srv, nil
+		}
+		srv.TLSConfig = opts.TLS
+		return tlsHTTPServer{srv}, nil
+	case model.TransportGRPC:
+		return &grpcServer{addr: ":" + addr, srv: NewGRPCServer(p, opts)}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", kind)
+	}
+}
+
+// tlsHTTPServer - HTTP-сервер, который слушает по TLS: сертификаты берутся из TLSConfig
+type tlsHTTPServer struct {
+	*http.Server
+}
+
+func (s tlsHTTPServer) ListenAndServe() error {
+	return s.ListenAndServeTLS("", "")
+}
+
+// NewSlaveServer создает HTTP-сервер slave-ноды; с opts.Auth эндпоинты требуют bearer-токен с нужной областью
+func NewSlaveServer(addr string, p TaskProcessor, opts ServerOptions) *http.Server {
+	opts = opts.withDefaults()
 	h := grepHandler{
-		Proc: p,
+		Proc:    p,
+		Metrics: opts.Metrics,
+		Limits:  opts.Limits,
+	}
+
+	ping := []ginext.HandlerFunc{h.HealthCheck}
+	metrics := []ginext.HandlerFunc{h.ServeMetrics}
+	task := []ginext.HandlerFunc{compression(opts), h.ReceiveTask}
+	if opts.Auth != nil {
+		ping = append([]ginext.HandlerFunc{requireScope(opts.Auth, ScopePing)}, ping...)
+		metrics = append([]ginext.HandlerFunc{requireScope(opts.Auth, ScopePing)}, metrics...)
+		task = append([]ginext.HandlerFunc{requireScope(opts.Auth, ScopeTask)}, task...)
 	}
 
 	engine := ginext.New("release")
-	engine.GET("/ping", h.HealthCheck)
-	engine.POST("/task", h.ReceiveTask)
+	engine.GET("/ping", ping...)
+	engine.GET("/metrics", metrics...)
+	engine.POST("/task", task...)
 
 	return &http.Server{
 		Addr:    ":" + addr,
@@ -33,15 +104,29 @@ func NewSlaveServer(addr string, p TaskProcessor) *http.Server {
 	}
 }
 
+// HealthCheck отвечает версией протокола, версией сборки и возможностями ноды(model.NodeInfo)
 func (gh grepHandler) HealthCheck(ctx *ginext.Context) {
-	ctx.Status(200)
+	ctx.JSON(http.StatusOK, localNodeInfo())
+}
+
+// ServeMetrics отдает счетчики сжатых и несжатых байт в формате Prometheus
+func (gh grepHandler) ServeMetrics(ctx *ginext.Context) {
+	ctx.Header("Content-Type", "text/plain; version=0.0.4")
+	ctx.Status(http.StatusOK)
+	gh.Metrics.WritePrometheus(ctx.Writer)
 }
 
+// ReceiveTask разбирает и проверяет задание(см. validateTask); отказ - TaskError со статусом 4xx
 func (gh grepHandler) ReceiveTask(ctx *ginext.Context) {
 	var task model.SlaveTask
 
 	if err := ctx.ShouldBindJSON(&task); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"failed to parse task from body: ": err.Error()})
+		te := bindError(err)
+		ctx.JSON(te.Status, te)
+		return
+	}
+	if te := validateTask(&task, gh.Limits); te != nil {
+		ctx.JSON(te.Status, te)
 		return
 	}
 
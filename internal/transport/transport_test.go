@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
@@ -22,7 +24,7 @@ func (m mockProcessor) ProcessInput(ctx context.Context, task *model.SlaveTask)
 }
 
 func TestHealthCheck(t *testing.T) {
-	srv := transport.NewSlaveServer("", mockProcessor{})
+	srv := transport.NewSlaveServer("", mockProcessor{}, transport.ServerOptions{})
 	require.NotEqual(t, nil, srv, "NewSlaveServer returned nil-server")
 
 	req := httptest.NewRequest("GET", "/ping", nil)
@@ -39,6 +41,7 @@ func TestReceiveTask(t *testing.T) {
 		mockProcFn *mockProcessor
 		ttask      *model.TaskDTO
 		wantCode   int
+		wantOutput []string
 	}{
 		{
 			name: "Positive - successful 200OK",
@@ -56,6 +59,23 @@ func TestReceiveTask(t *testing.T) {
 			},
 			wantCode: http.StatusOK,
 		},
+		{
+			name: "Positive - line longer than 64 KiB",
+			mockProcFn: &mockProcessor{
+				returnResultFn: func(ctx context.Context, task *model.SlaveTask) *model.SlaveResult {
+					return &model.SlaveResult{Output: []string{strconv.Itoa(len(task.Input[0]))}}
+				},
+			},
+			ttask: &model.TaskDTO{
+				TaskID: "taskID",
+				GP: model.GrepParam{
+					Pattern: "pattern",
+				},
+				Input: []string{strings.Repeat("a", 1<<20)},
+			},
+			wantCode:   http.StatusOK,
+			wantOutput: []string{"1048576"},
+		},
 		{
 			name: "Negative - empty task 400BadRequest",
 			mockProcFn: &mockProcessor{
@@ -70,7 +90,7 @@ func TestReceiveTask(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			srv := transport.NewSlaveServer("", tt.mockProcFn)
+			srv := transport.NewSlaveServer("", tt.mockProcFn, transport.ServerOptions{})
 			require.NotEqual(t, nil, srv, "NewSlaveServer returned nil-server")
 			raw, _ := json.Marshal(tt.ttask)
 			body := bytes.NewReader(raw)
@@ -81,6 +101,11 @@ func TestReceiveTask(t *testing.T) {
 			srv.Handler.ServeHTTP(w, req)
 
 			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantOutput != nil {
+				var res model.SlaveResult
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				require.Equal(t, tt.wantOutput, res.Output)
+			}
 		})
 	}
 }
@@ -0,0 +1,173 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Ограничения заданий slave-ноды по умолчанию
+const (
+	DefaultMaxBodySize     = 1 << 30 // тело задания, как оно пришло по сети
+	DefaultMaxTaskLines    = 1 << 24 // строк во входе одного задания
+	DefaultMaxTaskLineSize = 64 << 20
+)
+
+// Limits - ограничения заданий slave-ноды; нулевое поле - значение по умолчанию
+type Limits struct {
+	MaxBodySize int64 // байт тела /task до распаковки(у gRPC - сообщения, не больше MaxDecompressedSize)
+	MaxLines    int   // строк во входе задания
+	MaxLineSize int   // байт в одной строке входа
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxBodySize <= 0 {
+		l.MaxBodySize = DefaultMaxBodySize
+	}
+	if l.MaxLines <= 0 {
+		l.MaxLines = DefaultMaxTaskLines
+	}
+	if l.MaxLineSize <= 0 {
+		l.MaxLineSize = DefaultMaxTaskLineSize
+	}
+	return l
+}
+
+// коды TaskError
+const (
+	CodeMalformedTask = "malformed_task" // тело не разбирается как JSON задания или нет обязательных полей - 400
+	CodeInvalidTask   = "invalid_task"   // недопустимое значение поля - 422
+	CodeTaskTooLarge  = "task_too_large" // превышено одно из Limits или --max-decompressed-size - 413
+
+	CodeUnsupportedEncoding = "unsupported_encoding" // Content-Encoding не gzip - 415
+	CodeUnsupportedFeature  = "unsupported_feature"  // задание требует возможностей, которых у ноды нет - 422
+)
+
+// TaskError - отказ slave-ноды в задании. По HTTP отдается телом ответа со статусом Status:
+//
+//	{"error": "grep_param.pattern: error parsing regexp: ...", "code": "invalid_task", "field": "grep_param.pattern"}
+//
+// по gRPC - статусом InvalidArgument или ResourceExhausted с деталями BadRequest и ErrorInfo
+type TaskError struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"` // путь поля в JSON задания
+}
+
+func (te *TaskError) Error() string {
+	return te.Message
+}
+
+// GRPCStatus - статус для gRPC: status.Code и status.FromError понимают TaskError без преобразования
+func (te *TaskError) GRPCStatus() *status.Status {
+	code := codes.InvalidArgument
+	if te.Status == http.StatusRequestEntityTooLarge {
+		code = codes.ResourceExhausted
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: te.Code, Domain: "mygrep"}}
+	if te.Field != "" {
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: te.Field, Description: te.Message}},
+		})
+	}
+	st := status.New(code, te.Message)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// IsRejected сообщает, что slave-нода отказалась выполнять задание(4xx по HTTP, отказ по коду gRPC), а не
+// что задание не дошло до нее: повтор того же задания даст тот же отказ
+func IsRejected(err error) bool {
+	var te *TaskError
+	if errors.As(err, &te) {
+		return te.Status >= 400 && te.Status < 500
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.ResourceExhausted, codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	default:
+		return false
+	}
+}
+
+// bindError переводит ошибку разбора тела /task в TaskError
+func bindError(err error) *TaskError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) { // тело больше MaxBodySize или задание больше --max-decompressed-size после распаковки
+		return &TaskError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("task exceeds %d bytes", tooLarge.Limit),
+			Code:    CodeTaskTooLarge,
+		}
+	}
+	te := &TaskError{Status: http.StatusBadRequest, Message: "failed to parse task from body: " + err.Error(), Code: CodeMalformedTask}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		te.Field = typeErr.Field
+	}
+	return te
+}
+
+// validateTask проверяет задание до того, как оно попадет в TaskProcessor
+func validateTask(task *model.SlaveTask, lim Limits) *TaskError {
+	invalid := func(field, reason string) *TaskError {
+		return &TaskError{Status: http.StatusUnprocessableEntity, Message: field + ": " + reason, Code: CodeInvalidTask, Field: field}
+	}
+	tooLarge := func(field, reason string) *TaskError {
+		return &TaskError{Status: http.StatusRequestEntityTooLarge, Message: field + ": " + reason, Code: CodeTaskTooLarge, Field: field}
+	}
+
+	switch {
+	case task.TaskID == "":
+		return invalid("tid", "is required")
+	case len(task.Paths) != 0 && len(task.Input) != 0:
+		return invalid("paths", "can't be combined with input")
+	case len(task.Input) > lim.MaxLines:
+		return tooLarge("input", fmt.Sprintf("%d lines exceed the limit of %d", len(task.Input), lim.MaxLines))
+	case len(task.Offsets) != 0 && len(task.Offsets) != len(task.Input):
+		return invalid("offsets", fmt.Sprintf("%d offsets for %d input lines", len(task.Offsets), len(task.Input)))
+	case task.BaseOffset < 0:
+		return invalid("base_offset", "must not be negative")
+	case task.BaseLine < 0:
+		return invalid("base_line", "must not be negative")
+	case task.Carry != nil && (task.Carry.Before < 0 || task.Carry.Before > len(task.Input)):
+		return invalid("carry.before", fmt.Sprintf("must be between 0 and %d input lines", len(task.Input)))
+	case task.Carry != nil && (task.Carry.After < 0 || task.Carry.LastPrinted < 0):
+		return invalid("carry", "must not be negative")
+	}
+	for i, line := range task.Input {
+		if len(line) > lim.MaxLineSize {
+			return tooLarge(fmt.Sprintf("input[%d]", i), fmt.Sprintf("%d bytes exceed the limit of %d", len(line), lim.MaxLineSize))
+		}
+	}
+
+	// мастер новее ноды: лучше отказать, чем молча выполнить задание без части параметров
+	if missing := (&model.NodeInfo{Features: model.Features}).Missing(task.Features); len(missing) != 0 {
+		return &TaskError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "features: not supported by this slave-node: " + strings.Join(missing, ", "),
+			Code:    CodeUnsupportedFeature,
+			Field:   "features",
+		}
+	}
+
+	if err := task.GP.Validate(); err != nil {
+		var fe *model.FieldError
+		if errors.As(err, &fe) {
+			return invalid(fe.Field, fe.Reason)
+		}
+		return invalid("grep_param", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/reader"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testLimits = transport.Limits{MaxBodySize: 64 << 10, MaxLines: 10, MaxLineSize: 100}
+
+func TestValidateTask(t *testing.T) {
+	negative, maxCount := -1, 1
+	cases := []struct {
+		name      string
+		task      model.TaskDTO
+		wantCode  int
+		wantGRPC  codes.Code
+		wantErr   string
+		wantField string
+	}{
+		{
+			name:     "Positive - valid task",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "a+", MaxCount: &maxCount}, Input: []string{"a"}},
+			wantCode: http.StatusOK, wantGRPC: codes.OK,
+		},
+		{
+			name:     "Positive - invalid regexp with -F",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "a(", ExactMatch: true}, Input: []string{"a("}},
+			wantCode: http.StatusOK, wantGRPC: codes.OK,
+		},
+		{
+			name:     "Negative - regexp doesn't compile",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "a("}, Input: []string{"a"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "grep_param.pattern",
+		},
+		{
+			name:     "Negative - empty task ID",
+			task:     model.TaskDTO{GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}},
+			wantCode: http.StatusBadRequest, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeMalformedTask,
+		},
+		{
+			name:     "Negative - negative context",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p", CtxBefore: -3}, Input: []string{"a"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "grep_param.ctx_before",
+		},
+		{
+			name:     "Negative - negative max count",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p", MaxCount: &negative}, Input: []string{"a"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "grep_param.max_count",
+		},
+		{
+			name:     "Negative - unknown binary files mode",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p", BinaryFiles: "hex"}, Input: []string{"a"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "grep_param.binary_files",
+		},
+		{
+			name:     "Negative - -l together with -L",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p", FilesWithMatch: true, FilesWithoutMatch: true}, Input: []string{"a"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "grep_param.files_without_match",
+		},
+		{
+			name:     "Negative - offsets don't match input",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a", "b"}, Offsets: []int64{0}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "offsets",
+		},
+		{
+			name:     "Negative - carried context longer than input",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p", CtxBefore: 3}, Input: []string{"a", "b"}, Carry: &model.ContextCarry{Before: 3}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "carry.before",
+		},
+		{
+			name:     "Negative - paths together with input",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}, Paths: []string{"*.log"}},
+			wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument, wantErr: transport.CodeInvalidTask, wantField: "paths",
+		},
+		{
+			name:     "Negative - too many lines",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: make([]string, 11)},
+			wantCode: http.StatusRequestEntityTooLarge, wantGRPC: codes.ResourceExhausted, wantErr: transport.CodeTaskTooLarge, wantField: "input",
+		},
+		{
+			name:     "Negative - line too long",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a", strings.Repeat("a", 101)}},
+			wantCode: http.StatusRequestEntityTooLarge, wantGRPC: codes.ResourceExhausted, wantErr: transport.CodeTaskTooLarge, wantField: "input[1]",
+		},
+		{
+			name:     "Negative - body too large",
+			task:     model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{strings.Repeat("a", 100), strings.Repeat("\x01", 100) + string(make([]byte, 64<<10))}},
+			wantCode: http.StatusRequestEntityTooLarge, wantGRPC: codes.ResourceExhausted, wantErr: transport.CodeTaskTooLarge,
+		},
+	}
+
+	srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{Limits: testLimits})
+	grpcAddr := startSlave(t, model.TransportGRPC, echoProcessor, transport.ServerOptions{Limits: testLimits})
+	client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.task)
+			require.NoError(t, err)
+			req := httptest.NewRequest("POST", "/task", bytes.NewReader(raw))
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			require.Equal(t, tt.wantCode, w.Code, "body: %s", w.Body)
+			if tt.wantCode != http.StatusOK {
+				var te transport.TaskError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &te))
+				require.Equal(t, tt.wantErr, te.Code)
+				require.Equal(t, tt.wantField, te.Field)
+				require.NotEmpty(t, te.Message)
+			}
+
+			_, err = client.SendTask(context.Background(), grpcAddr, &tt.task)
+			st := status.Convert(err)
+			require.Equal(t, tt.wantGRPC, st.Code(), "error: %v", err)
+			require.Equal(t, tt.wantGRPC != codes.OK, transport.IsRejected(err))
+			if tt.wantField == "" {
+				return
+			}
+			var field string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					field = br.GetFieldViolations()[0].GetField()
+				}
+			}
+			require.Equal(t, tt.wantField, field)
+		})
+	}
+}
+
+// TestTruncatedLinesFitSlaveLimit: строки, обрезанные мастером по --long-lines=truncate, вместе с пометкой
+// укладываются в ограничение строки у slave-ноды с тем же лимитом
+func TestTruncatedLinesFitSlaveLimit(t *testing.T) {
+	const maxLine = 20
+	input := strings.Repeat("a", 100) + "\n" + strings.Repeat("ж", 50) + "\nshort\n"
+	lines, err := reader.ReadInput(strings.NewReader(input), "", reader.Options{MaxLineSize: maxLine, TruncateLongLines: true})
+	require.NoError(t, err)
+
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run(kind, func(t *testing.T) {
+			addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{Limits: transport.Limits{MaxLineSize: maxLine}})
+			client, err := transport.NewClient(kind, transport.ClientOptions{})
+			require.NoError(t, err)
+			defer client.Close()
+
+			res, err := client.SendTask(context.Background(), addr, &model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "a"}, Input: lines.Lines})
+			require.NoError(t, err)
+			require.Equal(t, append(lines.Lines, "", "a"), res.Output)
+			for _, line := range lines.Lines {
+				require.LessOrEqual(t, len(line), maxLine)
+			}
+		})
+	}
+}
+
+func TestReceiveTaskMalformed(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantField string
+	}{
+		{name: "Negative - not JSON", body: "tid=1", wantCode: http.StatusBadRequest},
+		{name: "Negative - truncated JSON", body: `{"tid":"t1","grep_param":{"pattern":"p"`, wantCode: http.StatusBadRequest},
+		{name: "Negative - wrong field type", body: `{"tid":"t1","grep_param":{"pattern":"p","ctx_after":"3"},"input":[]}`, wantCode: http.StatusBadRequest, wantField: "grep_param.ctx_after"},
+		{name: "Negative - missing input", body: `{"tid":"t1","grep_param":{"pattern":"p"}}`, wantCode: http.StatusBadRequest},
+	}
+	srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{Limits: testLimits})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/task", strings.NewReader(tt.body)))
+			require.Equal(t, tt.wantCode, w.Code)
+			var te transport.TaskError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &te))
+			require.Equal(t, transport.CodeMalformedTask, te.Code)
+			require.Equal(t, tt.wantField, te.Field)
+		})
+	}
+}
+
+// FuzzReceiveTask: на любое тело /task нода отвечает 200 с результатом или 4xx с TaskError, но не падает и не отдает 5xx
+func FuzzReceiveTask(f *testing.F) {
+	for _, seed := range []string{
+		`{"tid":"t1","grep_param":{"pattern":"a.c","ctx_after":1},"input":["abc","x"]}`,
+		`{"tid":"t1","grep_param":{"pattern":"(","exact_match":true},"input":["("],"offsets":[0]}`,
+		`{"tid":"t1","grep_param":{"pattern":"a","max_count":-1},"input":[]}`,
+		`{"tid":"","grep_param":{"pattern":"[z-a]"},"input":["a"],"paths":["*"]}`,
+		`{"tid":"t1","grep_param":{"pattern":"p","binary_files":"x","long_lines":"y"},"input":["a"],"base_line":-1}`,
+		`{"tid":1}`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{Limits: testLimits})
+	f.Fuzz(func(t *testing.T, body []byte) {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/task", bytes.NewReader(body)))
+
+		if w.Code == http.StatusOK {
+			var res model.SlaveResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			var task model.SlaveTask
+			require.NoError(t, json.Unmarshal(body, &task))
+			require.NoError(t, task.GP.Validate(), "accepted an invalid task")
+			require.LessOrEqual(t, len(task.Input), testLimits.MaxLines)
+			return
+		}
+		require.GreaterOrEqual(t, w.Code, 400)
+		require.Less(t, w.Code, 500)
+		var te transport.TaskError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &te), "body: %s", w.Body)
+		require.NotEmpty(t, te.Code)
+		require.NotEmpty(t, te.Message)
+	})
+}
@@ -0,0 +1,52 @@
+package transport
+
+import (
+	"runtime/debug"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+)
+
+// Version - версия сборки для /ping; задается при сборке:
+//
+//	go build -ldflags "-X github.com/UnendingLoop/DistributedGrepClone/internal/transport.Version=v1.2.3" ./cmd/app.go
+//
+// без нее берется версия модуля и коммит из информации о сборке
+var Version string
+
+// BuildVersion возвращает версию этой сборки
+func BuildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := bi.Main.Version
+	if version == "" { // go build app.go: без модуля в информации о сборке
+		version = "devel"
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+			version += "+" + s.Value[:12]
+		}
+	}
+	return version
+}
+
+// localNodeInfo - ответ на /ping этой slave-ноды
+func localNodeInfo() model.NodeInfo {
+	return model.NodeInfo{Protocol: model.ProtocolVersion, Version: BuildVersion(), Features: model.Features}
+}
+
+// legacyNodeInfo дополняет ответ slave-ноды до согласования возможностей: пустой ответ на /ping - это
+// протокол первой версии без возможностей
+func legacyNodeInfo(ni *model.NodeInfo) *model.NodeInfo {
+	if ni.Protocol == 0 {
+		ni.Protocol = 1
+	}
+	if ni.Version == "" {
+		ni.Version = "unknown"
+	}
+	return ni
+}
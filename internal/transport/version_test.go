@@ -0,0 +1,116 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/UnendingLoop/DistributedGrepClone/internal/model"
+	"github.com/UnendingLoop/DistributedGrepClone/internal/transport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPingNodeInfo(t *testing.T) {
+	transport.Version = "v9.9.9-test"
+	t.Cleanup(func() { transport.Version = "" })
+
+	for _, kind := range []string{model.TransportHTTP, model.TransportGRPC} {
+		t.Run(kind, func(t *testing.T) {
+			addr := startSlave(t, kind, echoProcessor, transport.ServerOptions{})
+			client, err := transport.NewClient(kind, transport.ClientOptions{})
+			require.NoError(t, err)
+			defer client.Close()
+
+			info, err := client.Ping(context.Background(), addr)
+			require.NoError(t, err)
+			require.Equal(t, &model.NodeInfo{Protocol: model.ProtocolVersion, Version: "v9.9.9-test", Features: model.Features}, info)
+		})
+	}
+}
+
+func TestPingLegacyNode(t *testing.T) {
+	// slave-нода до согласования возможностей: 200 без тела
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client, err := transport.NewClient(model.TransportHTTP, transport.ClientOptions{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	info, err := client.Ping(context.Background(), srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, 1, info.Protocol)
+	require.Empty(t, info.Features)
+	require.Equal(t, "unknown", info.Version)
+
+	maxCount := 1
+	gp := model.GrepParam{Pattern: "p", MaxCount: &maxCount, Color: true}
+	require.Equal(t, []string{model.FeatureMaxCount, model.FeatureColor}, info.Missing(gp.RequiredFeatures()))
+	require.Empty(t, info.Missing((&model.GrepParam{Pattern: "p", IgnoreCase: true, CtxAfter: 2}).RequiredFeatures()))
+}
+
+func TestRequiredFeatures(t *testing.T) {
+	sep := "=="
+	cases := []struct {
+		name    string
+		gp      model.GrepParam
+		wantRes []string
+	}{
+		{name: "Positive - first protocol version params", gp: model.GrepParam{CtxAfter: 1, CountFound: true, InvertResult: true, EnumLine: true}},
+		{name: "Positive - default binary files mode", gp: model.GrepParam{BinaryFiles: model.BinaryMatches, Encoding: "auto"}},
+		{name: "Positive - -a", gp: model.GrepParam{BinaryFiles: model.BinaryText}, wantRes: []string{model.FeatureBinaryFiles}},
+		{name: "Positive - -z -Z -b", gp: model.GrepParam{NullData: true, NullName: true, ByteOffset: true}, wantRes: []string{model.FeatureNullData, model.FeatureNullName, model.FeatureByteOffset}},
+		{name: "Positive - group separator", gp: model.GrepParam{GroupSeparator: &sep}, wantRes: []string{model.FeatureGroupSeparator}},
+		{name: "Positive - archives read by the master", gp: model.GrepParam{Archives: true, Encoding: "latin1"}},
+		{name: "Positive - archives read by slave-nodes", gp: model.GrepParam{Remote: true, Archives: true, Encoding: "latin1"}, wantRes: []string{model.FeatureRemote, model.FeatureArchives, model.FeatureEncoding}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantRes, tt.gp.RequiredFeatures())
+			require.Empty(t, (&model.NodeInfo{Features: model.Features}).Missing(tt.gp.RequiredFeatures()))
+		})
+	}
+}
+
+func TestTaskFeatures(t *testing.T) {
+	cases := []struct {
+		name     string
+		features []string
+		wantCode int
+		wantGRPC codes.Code
+	}{
+		{name: "Positive - no features", wantCode: http.StatusOK, wantGRPC: codes.OK},
+		{name: "Positive - supported features", features: []string{model.FeatureColor, model.FeatureHeading}, wantCode: http.StatusOK, wantGRPC: codes.OK},
+		{name: "Negative - feature of a newer master", features: []string{model.FeatureColor, "regex-v2"}, wantCode: http.StatusUnprocessableEntity, wantGRPC: codes.InvalidArgument},
+	}
+
+	srv := transport.NewSlaveServer("", echoProcessor, transport.ServerOptions{})
+	grpcAddr := startSlave(t, model.TransportGRPC, echoProcessor, transport.ServerOptions{})
+	client, err := transport.NewClient(model.TransportGRPC, transport.ClientOptions{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			task := model.TaskDTO{TaskID: "t1", GP: model.GrepParam{Pattern: "p"}, Input: []string{"a"}, Features: tt.features}
+			raw, err := json.Marshal(task)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/task", bytes.NewReader(raw)))
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				var te transport.TaskError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &te))
+				require.Equal(t, transport.CodeUnsupportedFeature, te.Code)
+				require.Contains(t, te.Message, "regex-v2")
+			}
+
+			_, err = client.SendTask(context.Background(), grpcAddr, &task)
+			require.Equal(t, tt.wantGRPC, status.Code(err), "error: %v", err)
+		})
+	}
+}